@@ -0,0 +1,55 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	trello "github.com/jnormington/go-trello"
+)
+
+// getTimeInLists works out from the card actions how long the card
+// has spent in each list, the current list counts up until now
+func getTimeInLists(card *trello.Card, actions []trello.Action) map[string]time.Duration {
+	type move struct {
+		at   time.Time
+		list string
+	}
+
+	var moves []move
+	for _, a := range actions {
+		d := parseDateOrReturnNil(a.Date)
+		if d == nil {
+			continue
+		}
+
+		switch {
+		case a.Type == "createCard" && a.Data.List.Name != "":
+			moves = append(moves, move{at: *d, list: a.Data.List.Name})
+		case a.Type == "updateCard" && a.Data.ListAfter.Name != "":
+			if len(moves) == 0 && a.Data.ListBefore.Name != "" {
+				// The create action is out of the history so the time
+				// in the first list is unknown, start from this move
+				moves = append(moves, move{at: *d, list: a.Data.ListBefore.Name})
+			}
+			moves = append(moves, move{at: *d, list: a.Data.ListAfter.Name})
+		}
+	}
+
+	durations := map[string]time.Duration{}
+	if len(moves) == 0 {
+		return durations
+	}
+
+	sort.Slice(moves, func(i, j int) bool { return moves[i].at.Before(moves[j].at) })
+
+	for i, m := range moves {
+		end := time.Now()
+		if i+1 < len(moves) {
+			end = moves[i+1].at
+		}
+
+		durations[m.list] += end.Sub(m.at)
+	}
+
+	return durations
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+)
+
+const (
+	annotateCardsComment    = "comment"
+	annotateCardsAttachment = "attachment"
+
+	migratedLabelColor = "green"
+)
+
+// CardAnnotator updates the original trello cards once their story is
+// created so both systems stay consistent during a gradual cutover
+type CardAnnotator struct {
+	Mode    string
+	LabelID string
+	Archive bool
+}
+
+// newCardAnnotator returns nil when the cards are left untouched, the
+// migrated label is found on the board or created when missing
+func newCardAnnotator(mode string, label string, archive bool, boardID string) *CardAnnotator {
+	if mode == "" && label == "" && !archive {
+		return nil
+	}
+
+	a := &CardAnnotator{Mode: mode, Archive: archive}
+	if label == "" {
+		return a
+	}
+
+	labels, err := getBoardLabels(boardID)
+	if err != nil {
+		log.Fatalf("Error querying the board labels: %s", err)
+	}
+
+	for _, l := range labels {
+		if strings.EqualFold(l.Name, label) {
+			a.LabelID = l.ID
+			return a
+		}
+	}
+
+	var l trelloLabel
+	params := url.Values{"name": {label}, "color": {migratedLabelColor}, "idBoard": {boardID}}
+	if err := trelloRequest("POST", "/labels", params, &l); err != nil {
+		log.Fatalf("Error creating the migrated label '%s': %s", label, err)
+	}
+
+	a.LabelID = l.ID
+	return a
+}
+
+// Annotate links the card to its clubhouse url, labels it as migrated
+// and archives it, errors are ignored as the story has already been created
+func (a *CardAnnotator) Annotate(card *Card, kind string, id int64, clubhouseURL string) {
+	if a == nil {
+		return
+	}
+
+	var err error
+	switch a.Mode {
+	case annotateCardsComment:
+		text := fmt.Sprintf("Migrated to Clubhouse: %s", clubhouseURL)
+		err = trelloRequest("POST", "/cards/"+card.ID+"/actions/comments", url.Values{"text": {text}}, nil)
+	case annotateCardsAttachment:
+		params := url.Values{"url": {clubhouseURL}, "name": {"Clubhouse"}}
+		err = trelloRequest("POST", "/cards/"+card.ID+"/attachments", params, nil)
+	}
+
+	if err != nil {
+		fmt.Println("Error: Adding the clubhouse link to card:", card.Name, "ignoring...", err)
+	}
+
+	if a.LabelID != "" {
+		err = trelloRequest("POST", "/cards/"+card.ID+"/idLabels", url.Values{"value": {a.LabelID}}, nil)
+		if err != nil {
+			fmt.Println("Error: Adding the migrated label to card:", card.Name, "ignoring...", err)
+		}
+	}
+
+	if a.Archive {
+		a.archive(card, kind, id)
+	}
+}
+
+// archive closes the card once clubhouse returns the created story or
+// epic so nobody keeps working on the card after it has moved
+func (a *CardAnnotator) archive(card *Card, kind string, id int64) {
+	if err := clubhouseRequest("GET", fmt.Sprintf("/%ss/%d", kind, id), nil, nil); err != nil {
+		fmt.Println("Error: Not archiving card:", card.Name, "the clubhouse", kind, "couldn't be confirmed", err)
+		return
+	}
+
+	if err := trelloRequest("PUT", "/cards/"+card.ID, url.Values{"closed": {"true"}}, nil); err != nil {
+		fmt.Println("Error: Archiving card:", card.Name, "ignoring...", err)
+	}
+}
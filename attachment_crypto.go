@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+)
+
+const (
+	encryptedSuffix    = ".enc"
+	encryptedMagic     = "T2CENC1\n"
+	encryptChunkSize   = 64 * 1024
+	encryptNoncePrefix = 7
+	encryptNote        = "AES-256-GCM encrypted, decrypt with: trello-to-clubhouse.io decrypt -key-file <key file> <file>"
+)
+
+var errBadAttachmentKey = errors.New("unable to decrypt the attachment, is the key correct and the file complete ?")
+
+// loadAttachmentKey reads the passphrase the attachments are encrypted with
+func loadAttachmentKey(path string) string {
+	if path == "" {
+		return ""
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		fatalConfigf("Error reading the attachment key file: %s", err)
+	}
+
+	key := strings.TrimSpace(string(b))
+	if key == "" {
+		fatalConfig("The attachment key file is empty")
+	}
+
+	return key
+}
+
+// encryptAttachment encrypts in chunks so large files aren't held in memory.
+// The layout is magic | salt | nonce prefix | sealed chunks, each chunk nonce is
+// the prefix, the chunk number and whether it is the last so chunks can't be
+// reordered or the file truncated without failing to decrypt
+func encryptAttachment(w io.Writer, r io.Reader, passphrase string) error {
+	salt := make([]byte, credentialsSaltLen)
+	prefix := make([]byte, encryptNoncePrefix)
+	for _, b := range [][]byte{salt, prefix} {
+		if _, err := io.ReadFull(rand.Reader, b); err != nil {
+			return err
+		}
+	}
+
+	gcm, err := newCredentialsCipher(passphrase, salt)
+	if err != nil {
+		return err
+	}
+
+	header := append(append([]byte(encryptedMagic), salt...), prefix...)
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	br := bufio.NewReader(r)
+	chunk := make([]byte, encryptChunkSize)
+	for n := uint32(0); ; n++ {
+		read, err := io.ReadFull(br, chunk)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+
+		_, err = br.Peek(1)
+		last := err == io.EOF
+
+		if _, err := w.Write(gcm.Seal(nil, chunkNonce(prefix, n, last), chunk[:read], nil)); err != nil {
+			return err
+		}
+
+		if last {
+			return nil
+		}
+	}
+}
+
+// decryptAttachment reverses encryptAttachment
+func decryptAttachment(w io.Writer, r io.Reader, passphrase string) error {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, len(encryptedMagic)+credentialsSaltLen+encryptNoncePrefix)
+	if _, err := io.ReadFull(br, header); err != nil || string(header[:len(encryptedMagic)]) != encryptedMagic {
+		return errors.New("not an encrypted attachment")
+	}
+
+	salt := header[len(encryptedMagic) : len(encryptedMagic)+credentialsSaltLen]
+	prefix := header[len(encryptedMagic)+credentialsSaltLen:]
+
+	gcm, err := newCredentialsCipher(passphrase, salt)
+	if err != nil {
+		return err
+	}
+
+	sealed := make([]byte, encryptChunkSize+gcm.Overhead())
+	for n := uint32(0); ; n++ {
+		read, err := io.ReadFull(br, sealed)
+		if err == io.EOF {
+			return errBadAttachmentKey
+		} else if err != nil && err != io.ErrUnexpectedEOF {
+			return err
+		}
+
+		_, err = br.Peek(1)
+		last := err == io.EOF
+
+		plain, err := gcm.Open(nil, chunkNonce(prefix, n, last), sealed[:read], nil)
+		if err != nil {
+			return errBadAttachmentKey
+		}
+
+		if _, err := w.Write(plain); err != nil {
+			return err
+		}
+
+		if last {
+			return nil
+		}
+	}
+}
+
+func chunkNonce(prefix []byte, n uint32, last bool) []byte {
+	nonce := make([]byte, encryptNoncePrefix+5)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[encryptNoncePrefix:], n)
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+
+	return nonce
+}
+
+// Encrypt replaces the staged attachment with its encrypted copy,
+// the hashes are of the encrypted copy so the upload can be verified
+func (s *stagedAttachment) Encrypt(passphrase string) error {
+	in, err := os.Open(s.Path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp, err := ioutil.TempFile("", "trello-attachment-enc-")
+	if err != nil {
+		return err
+	}
+
+	sh := sha256.New()
+	ch := newDropboxContentHash()
+	cw := &countingWriter{}
+
+	err = encryptAttachment(io.MultiWriter(tmp, sh, ch, cw), in, passphrase)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	in.Close()
+	s.Remove()
+
+	s.Path = tmp.Name()
+	s.Size = cw.n
+	s.SHA256 = hex.EncodeToString(sh.Sum(nil))
+	s.ContentHash = ch.Hex()
+
+	return nil
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// runDecryptCommand decrypts an attachment downloaded from dropbox
+func runDecryptCommand(args []string) {
+	fs := flag.NewFlagSet("decrypt", flag.ExitOnError)
+	keyFile := fs.String("key-file", "", "file with the key the attachments were encrypted with")
+	out := fs.String("o", "", "path the decrypted file is written to, the file without .enc by default")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 || *keyFile == "" {
+		fatalConfig("Usage: trello-to-clubhouse.io decrypt -key-file <key file> [-o <output>] <file>")
+	}
+
+	in := fs.Arg(0)
+	if *out == "" {
+		*out = strings.TrimSuffix(in, encryptedSuffix)
+		if *out == in {
+			*out = in + ".dec"
+		}
+	}
+
+	r, err := os.Open(in)
+	if err != nil {
+		log.Fatalf("Error opening the encrypted file: %s", err)
+	}
+	defer r.Close()
+
+	w, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("Error creating the decrypted file: %s", err)
+	}
+
+	err = decryptAttachment(w, r, loadAttachmentKey(*keyFile))
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		os.Remove(*out)
+		log.Fatalf("Error decrypting %s: %s", in, err)
+	}
+
+	log.Printf("Decrypted %s to %s", in, *out)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"mime"
+	"path"
+	"strings"
+
+	trello "github.com/jnormington/go-trello"
+)
+
+// AttachmentFilter decides which attachments are migrated by their mime type
+type AttachmentFilter struct {
+	Include []string
+	Exclude []string
+}
+
+// Allows returns true when the attachment matches an include type, if any
+// are given, and doesn't match any of the exclude types
+func (af AttachmentFilter) Allows(a *trello.Attachment) bool {
+	t := attachmentMimeType(a)
+
+	if len(af.Include) > 0 && !matchesAnyMimeType(t, af.Include) {
+		return false
+	}
+
+	return !matchesAnyMimeType(t, af.Exclude)
+}
+
+func attachmentMimeType(a *trello.Attachment) string {
+	if a.MimeType != "" {
+		return strings.ToLower(a.MimeType)
+	}
+
+	// Older attachments don't always have a mime type from the api
+	t := mime.TypeByExtension(strings.ToLower(path.Ext(a.Name)))
+	if i := strings.Index(t, ";"); i != -1 {
+		t = t[:i]
+	}
+
+	return t
+}
+
+func matchesAnyMimeType(t string, patterns []string) bool {
+	for _, p := range patterns {
+		p = strings.ToLower(p)
+
+		if strings.HasSuffix(p, "/*") {
+			if strings.HasPrefix(t, strings.TrimSuffix(p, "*")) {
+				return true
+			}
+		} else if p == t {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	trello "github.com/jnormington/go-trello"
+	"github.com/tj/go-dropbox"
+)
+
+const manifestFileName = "manifest.json"
+
+// AttachmentManifest is written next to the uploaded attachments of a card
+// so the files can be traced back to trello without the migration report
+type AttachmentManifest struct {
+	CardID      string                    `json:"card_id"`
+	CardName    string                    `json:"card_name"`
+	CardURL     string                    `json:"card_url"`
+	BoardName   string                    `json:"board_name"`
+	ListName    string                    `json:"list_name"`
+	MigratedAt  string                    `json:"migrated_at"`
+	Attachments []AttachmentManifestEntry `json:"attachments"`
+}
+
+// AttachmentManifestEntry is a single uploaded attachment in the manifest
+type AttachmentManifestEntry struct {
+	Name        string `json:"name"`
+	Path        string `json:"path"`
+	OriginalURL string `json:"original_url"`
+	UploadedAt  string `json:"uploaded_at"`
+	UploaderID  string `json:"uploader_id"`
+	Uploader    string `json:"uploader,omitempty"`
+	MimeType    string `json:"mime_type,omitempty"`
+	Bytes       int    `json:"bytes"`
+	SharedLink  string `json:"shared_link"`
+	Encryption  string `json:"encryption,omitempty"`
+}
+
+// cardManifests collects the uploaded attachments of a card by dropbox folder
+type cardManifests struct {
+	mu      sync.Mutex
+	entries map[string][]AttachmentManifestEntry
+}
+
+func (cm *cardManifests) Add(t *TrelloOptions, f *trello.Attachment, p string, link string) {
+	e := AttachmentManifestEntry{
+		Name:        f.Name,
+		Path:        p,
+		OriginalURL: f.Url,
+		UploadedAt:  f.Date,
+		UploaderID:  f.IdMember,
+		Uploader:    t.memberNames[f.IdMember],
+		MimeType:    f.MimeType,
+		Bytes:       f.Bytes,
+		SharedLink:  link,
+	}
+
+	if t.AttachmentKey != "" {
+		e.Encryption = encryptNote
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if cm.entries == nil {
+		cm.entries = map[string][]AttachmentManifestEntry{}
+	}
+
+	dir := path.Dir(p)
+	cm.entries[dir] = append(cm.entries[dir], e)
+}
+
+// Upload writes a manifest in every folder the card attachments were uploaded to,
+// a manifest which fails to upload is only a warning as the attachments are fine
+func (cm *cardManifests) Upload(t *TrelloOptions, card *trello.Card) {
+	dirs := make([]string, 0, len(cm.entries))
+	for d := range cm.entries {
+		dirs = append(dirs, d)
+	}
+	sort.Strings(dirs)
+
+	for _, d := range dirs {
+		m := AttachmentManifest{
+			CardID:      card.Id,
+			CardName:    card.Name,
+			CardURL:     card.ShortUrl,
+			BoardName:   t.Board.Name,
+			ListName:    t.List.Name,
+			MigratedAt:  time.Now().UTC().Format(time.RFC3339),
+			Attachments: cm.entries[d],
+		}
+
+		b, err := json.MarshalIndent(m, "", "  ")
+		if err != nil {
+			fmt.Println("Warning: Building the attachment manifest for card:", card.Name, err)
+			continue
+		}
+
+		p := t.DropboxPaths.ManifestPath(card, d)
+		err = retryOnHardLimit(p, func() error {
+			return withDropbox(func(c *dropbox.Client) error {
+				u := dropbox.UploadInput{Path: p, Mode: "overwrite", AutoRename: false, Mute: true,
+					ClientModified: clientModifiedNow(), Reader: bytes.NewReader(b)}
+
+				_, err := c.Files.Upload(&u)
+				return err
+			})
+		})
+
+		if err != nil {
+			fmt.Println("Warning: Uploading the attachment manifest:", p, "for card:", card.Name, err)
+		}
+	}
+}
+
+// loadManifestMemberNames looks up the board members so the
+// manifests name who uploaded each attachment
+func (t *TrelloOptions) loadManifestMemberNames() {
+	if !t.Attachments.Enabled || !t.Manifests {
+		return
+	}
+
+	t.memberNames = map[string]string{}
+	for _, m := range *t.ListMembers() {
+		t.memberNames[m.Id] = fmt.Sprintf("%s (%s)", m.FullName, m.Username)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	trello "github.com/jnormington/go-trello"
+)
+
+const attachmentBackendDropbox = "dropbox"
+
+// AttachmentOptions are how the card attachments are migrated,
+// the zero value leaves them on the trello cards
+type AttachmentOptions struct {
+	Enabled      bool
+	Backend      string
+	Filter       AttachmentFilter
+	InlineImages bool
+	MaxSize      int64
+}
+
+// newAttachmentOptions builds the options of the config, the max size was validated
+func newAttachmentOptions(cfg *Config) AttachmentOptions {
+	max, _ := parseByteSize(cfg.MaxAttachmentSize)
+
+	return AttachmentOptions{
+		Enabled:      cfg.MigrateAttachments,
+		Backend:      cfg.AttachmentBackend,
+		Filter:       AttachmentFilter{Include: cfg.AttachmentTypes, Exclude: cfg.ExcludeAttachmentTypes},
+		InlineImages: cfg.InlineImages,
+		MaxSize:      max,
+	}
+}
+
+// Allows returns true when the attachment passes the type filter and the max size
+func (ao AttachmentOptions) Allows(a *trello.Attachment) bool {
+	return ao.Filter.Allows(a) && !ao.TooLarge(a)
+}
+
+// TooLarge returns true for an uploaded file over the max size, links have no size
+func (ao AttachmentOptions) TooLarge(a *trello.Attachment) bool {
+	return ao.MaxSize > 0 && a.IsUpload && int64(a.Bytes) > ao.MaxSize
+}
+
+func (ao AttachmentOptions) String() string {
+	if !ao.Enabled {
+		return "no"
+	}
+
+	s := "to " + ao.Backend
+	if len(ao.Filter.Include) > 0 {
+		s += ", only " + strings.Join(ao.Filter.Include, ",")
+	}
+	if len(ao.Filter.Exclude) > 0 {
+		s += ", not " + strings.Join(ao.Filter.Exclude, ",")
+	}
+	if ao.MaxSize > 0 {
+		s += ", up to " + megabytes(ao.MaxSize)
+	}
+	if ao.InlineImages {
+		s += ", images inline"
+	}
+
+	return s
+}
+
+// promptUser asks whether to migrate the attachments and show the images
+// in the descriptions, unless they were given with -non-interactive
+func (ao *AttachmentOptions) promptUser() {
+	if nonInteractive {
+		if ao.Enabled && !dropboxConfigured() {
+			fatalConfig("Dropbox token not supplied unable to continue")
+		}
+		return
+	}
+
+	fmt.Println("Would you like to migrate all attachments from trello cards?")
+	fmt.Println("This will entail downloading the attachments and uploading to dropbox")
+	fmt.Println("A dropbox account will be required for the token")
+
+	if promptYesNo() != 0 {
+		return
+	}
+
+	ao.Enabled = true
+	if !dropboxConfigured() {
+		fatalConfig("Dropbox token not supplied unable to continue")
+	}
+
+	if ao.InlineImages {
+		return
+	}
+
+	fmt.Println("Would you like the image attachments shown in the story descriptions too?")
+	ao.InlineImages = promptYesNo() == 0
+}
+
+func promptYesNo() int {
+	for i, b := range yesNoOpts {
+		fmt.Printf("[%d] %s\n", i, b)
+	}
+
+	i := promptUserSelectResource()
+	if i >= len(yesNoOpts) {
+		log.Fatal(errOutOfRange)
+	}
+
+	return i
+}
+
+// parseByteSize parses a size in bytes with an optional KB, MB or GB suffix, empty is no size
+func parseByteSize(size string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(size))
+	if s == "" {
+		return 0, nil
+	}
+
+	unit := int64(1)
+	for _, u := range []struct {
+		suffix string
+		size   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(s, u.suffix) {
+			s, unit = strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), u.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size '%s' expected bytes or a number with KB, MB or GB e.g. 25MB", size)
+	}
+
+	return int64(n * float64(unit)), nil
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+)
+
+const (
+	trelloAuthorizeURL  = "https://trello.com/1/authorize"
+	dropboxAuthorizeURL = "https://www.dropbox.com/oauth2/authorize"
+	dropboxTokenURL     = "https://api.dropboxapi.com/oauth2/token"
+	trelloAppName       = "MigrationFromTrelloToClubhouse"
+
+	// The callbacks are listened for and redirected to on the same host,
+	// localhost could resolve to the ipv6 address instead
+	authCallbackHost = "127.0.0.1"
+)
+
+// The trello token is returned in the url fragment which never reaches
+// the server so this page posts it back to us
+const trelloCallbackPage = `<html><body><script>
+var t = window.location.hash.replace("#token=", "");
+fetch("/trello/token?token=" + encodeURIComponent(t)).then(function() {
+	document.body.innerText = "Trello token received, you can close this window";
+});
+</script></body></html>`
+
+type authFlow struct {
+	Port          int
+	DropboxAppKey string
+
+	listener     net.Listener
+	results      chan authResult
+	dropboxState string
+}
+
+type authResult struct {
+	service string
+	token   string
+	err     error
+}
+
+// runAuthCommand walks the user through the Trello and Dropbox
+// authorization in their browser and stores the tokens encrypted
+func runAuthCommand(args []string) {
+	var a authFlow
+
+	fs := flag.NewFlagSet("auth", flag.ExitOnError)
+	fs.IntVar(&a.Port, "port", 8089, "local port used to receive the OAuth callbacks")
+	fs.StringVar(&a.DropboxAppKey, "dropbox-app-key", "", "app key of your dropbox app (redirect uri http://127.0.0.1:<port>/dropbox)")
+	fs.Parse(args)
+
+	services := fs.Args()
+	if len(services) == 0 {
+		services = []string{"trello", "dropbox"}
+	}
+
+	passphrase := promptPassphrase()
+	c := &Credentials{}
+	if credentialsExist() {
+		var err error
+		c, err = loadCredentials(passphrase)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if err := a.listen(); err != nil {
+		log.Fatalf("Error starting local server for the OAuth callback: %s", err)
+	}
+	defer a.listener.Close()
+
+	for _, s := range services {
+		switch s {
+		case "trello":
+			c.TrelloKey, c.TrelloToken = a.authorizeTrello(c.TrelloKey)
+		case "dropbox":
+			c.DropboxToken, c.DropboxRefreshToken = a.authorizeDropbox()
+			c.DropboxAppKey = a.DropboxAppKey
+		default:
+			log.Fatalf("Unknown service '%s' expected trello or dropbox", s)
+		}
+	}
+
+	if err := saveCredentials(c, passphrase); err != nil {
+		log.Fatalf("Error saving credentials: %s", err)
+	}
+
+	fmt.Printf("*********************\n Credentials saved: %s\n*********************\n", getCredentialsPath())
+}
+
+func (a *authFlow) listen() error {
+	l, err := net.Listen("tcp", fmt.Sprintf("%s:%d", authCallbackHost, a.Port))
+	if err != nil {
+		return err
+	}
+
+	a.listener = l
+	a.results = make(chan authResult)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/trello", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, trelloCallbackPage)
+	})
+	mux.HandleFunc("/trello/token", func(w http.ResponseWriter, r *http.Request) {
+		a.results <- authResult{service: "trello", token: r.URL.Query().Get("token")}
+	})
+	mux.HandleFunc("/dropbox", func(w http.ResponseWriter, r *http.Request) {
+		if e := r.URL.Query().Get("error_description"); e != "" {
+			a.results <- authResult{service: "dropbox", err: fmt.Errorf("%s", e)}
+			return
+		}
+
+		// Only the authorization we started is accepted
+		if a.dropboxState == "" || r.URL.Query().Get("state") != a.dropboxState {
+			http.Error(w, "Invalid state", http.StatusBadRequest)
+			a.results <- authResult{service: "dropbox", err: fmt.Errorf("the callback state doesn't match the authorization")}
+			return
+		}
+
+		fmt.Fprint(w, "Dropbox authorization received, you can close this window")
+		a.results <- authResult{service: "dropbox", token: r.URL.Query().Get("code")}
+	})
+
+	go http.Serve(l, mux)
+	return nil
+}
+
+func (a *authFlow) callbackURL(service string) string {
+	return fmt.Sprintf("http://%s:%d/%s", authCallbackHost, a.Port, service)
+}
+
+func (a *authFlow) authorizeTrello(key string) (string, string) {
+	if key == "" {
+		key = trelloKey
+	}
+
+	if tokenMissing(key) {
+		fmt.Println("Please enter your Trello key, you can get the key here https://trello.com/app-key")
+		key = promptUserForText()
+	}
+
+	v := url.Values{}
+	v.Set("expiration", "never")
+	v.Set("name", trelloAppName)
+	v.Set("scope", "read,write")
+	v.Set("response_type", "token")
+	v.Set("callback_method", "fragment")
+	v.Set("return_url", a.callbackURL("trello"))
+	v.Set("key", key)
+
+	r := a.waitForBrowser("Trello", trelloAuthorizeURL+"?"+v.Encode())
+	return key, r.token
+}
+
+// authorizeDropbox returns the access token and the refresh token
+// used to renew it as the access tokens expire after a few hours
+func (a *authFlow) authorizeDropbox() (string, string) {
+	if a.DropboxAppKey == "" {
+		fmt.Println("Please enter the app key of your dropbox app, you can create one here https://www.dropbox.com/developers/apps/create")
+		a.DropboxAppKey = promptUserForText()
+	}
+
+	verifier := randomURLSafeString(32)
+	challenge := sha256.Sum256([]byte(verifier))
+	a.dropboxState = randomURLSafeString(16)
+
+	v := url.Values{}
+	v.Set("client_id", a.DropboxAppKey)
+	v.Set("response_type", "code")
+	v.Set("redirect_uri", a.callbackURL("dropbox"))
+	v.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	v.Set("code_challenge_method", "S256")
+	v.Set("token_access_type", "offline")
+	v.Set("state", a.dropboxState)
+
+	r := a.waitForBrowser("Dropbox", dropboxAuthorizeURL+"?"+v.Encode())
+
+	token, refresh, err := a.exchangeDropboxCode(r.token, verifier)
+	if err != nil {
+		log.Fatalf("Error exchanging dropbox authorization code: %s", err)
+	}
+
+	return token, refresh
+}
+
+func (a *authFlow) exchangeDropboxCode(code string, verifier string) (string, string, error) {
+	resp, err := http.PostForm(dropboxTokenURL, url.Values{
+		"code":          {code},
+		"grant_type":    {"authorization_code"},
+		"client_id":     {a.DropboxAppKey},
+		"redirect_uri":  {a.callbackURL("dropbox")},
+		"code_verifier": {verifier},
+	})
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		Error        string `json:"error_description"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", "", err
+	}
+
+	if out.AccessToken == "" {
+		return "", "", fmt.Errorf("no access token returned: %s", out.Error)
+	}
+
+	return out.AccessToken, out.RefreshToken, nil
+}
+
+func (a *authFlow) waitForBrowser(service string, u string) authResult {
+	fmt.Printf("Opening your browser to authorize %s, if it doesn't open visit:\n%s\n", service, u)
+	openBrowser(u)
+
+	r := <-a.results
+	if r.err != nil {
+		log.Fatalf("Error authorizing %s: %s", service, r.err)
+	}
+
+	if r.token == "" {
+		log.Fatalf("No token was received from %s", service)
+	}
+
+	fmt.Printf("%s authorized\n", service)
+	return r
+}
+
+func openBrowser(u string) {
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", u)
+	case "darwin":
+		cmd = exec.Command("open", u)
+	default:
+		cmd = exec.Command("xdg-open", u)
+	}
+
+	// Failing to open is fine the url has been printed
+	cmd.Start()
+}
+
+func randomURLSafeString(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
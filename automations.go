@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// AutomationReport is an automation enabled on the board which has to be
+// recreated in clubhouse as it isn't migrated
+type AutomationReport struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	Note string `json:"note,omitempty"`
+}
+
+// The public api doesn't expose the butler rules themselves so butler is
+// reported with where to find them
+const butlerNote = "the rules, buttons and schedules are listed in the board Automation menu and must be recreated by hand"
+
+// getBoardAutomations lists the power-ups enabled on the board, butler
+// included, and adds them to the report
+func getBoardAutomations(boardID string) []AutomationReport {
+	var plugins []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+
+	err := trelloRequest("GET", "/boards/"+boardID+"/plugins", url.Values{"filter": {"enabled"}}, &plugins)
+	if err != nil {
+		fmt.Println("Error: Querying the board power-ups ignoring...", err)
+		return nil
+	}
+
+	// Butler is built into every board so it is always listed
+	automations := []AutomationReport{{Name: "Butler", Kind: "butler", Note: butlerNote}}
+	for _, p := range plugins {
+		if strings.Contains(strings.ToLower(p.Name), "butler") {
+			continue
+		}
+		automations = append(automations, AutomationReport{Name: p.Name, Kind: "power-up"})
+	}
+
+	sort.SliceStable(automations[1:], func(i, j int) bool { return automations[i+1].Name < automations[j+1].Name })
+
+	fmt.Println("These board automations are not migrated and need recreating as clubhouse integrations:")
+	for _, a := range automations {
+		fmt.Printf("\t%s (%s)\n", a.Name, a.Kind)
+	}
+
+	report.SetAutomations(automations)
+	return automations
+}
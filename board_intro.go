@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	ch "github.com/jnormington/clubhouse-go"
+)
+
+const (
+	boardIntroStory = "story"
+	boardIntroEpic  = "epic"
+)
+
+// boardDetails is what the board intro is built from
+type boardDetails struct {
+	Name  string `json:"name"`
+	Desc  string `json:"desc"`
+	URL   string `json:"url"`
+	Prefs struct {
+		Background      string `json:"background"`
+		BackgroundImage string `json:"backgroundImage"`
+	} `json:"prefs"`
+	Organization struct {
+		DisplayName string `json:"displayName"`
+		URL         string `json:"url"`
+	} `json:"organization"`
+}
+
+// importBoardIntro creates an introductory story or epic with the board description,
+// background and links so the context kept at the board level isn't lost
+func importBoardIntro(kind string, to *TrelloOptions, co *ClubhouseOptions) {
+	if kind == "" {
+		return
+	}
+
+	if co.Mode == modeUpdate {
+		// The intro was created by the first run
+		return
+	}
+
+	var b boardDetails
+	params := url.Values{"fields": {"name,desc,url,prefs"}, "organization": {"true"}, "organization_fields": {"displayName,url"}}
+	if err := trelloRequest("GET", "/boards/"+to.Board.Id, params, &b); err != nil {
+		fmt.Println("Error: Querying the board description ignoring...", err)
+		return
+	}
+
+	name := fmt.Sprintf("About the %s board", b.Name)
+	desc := buildBoardIntro(&b, to)
+
+	if co.DryRun {
+		cardOutput.Row(b.URL, "Would Create", name)
+		return
+	}
+
+	var id int64
+	var err error
+	if kind == boardIntroEpic {
+		var e clubhouseEntity
+		err = clubhouseRequest("POST", "/epics", createEpic{Name: name, Description: desc, OwnerIds: []string{}, Labels: []ch.CreateLabel{}}, &e)
+		id = e.ID
+	} else {
+		var st ch.Story
+		st, err = co.ClubhouseEntry.CreateStory(ch.CreateStory{
+			ProjectID:       co.Project.ID,
+			WorkflowStateID: co.State.ID,
+			StoryType:       "chore",
+			Name:            name,
+			Description:     desc,
+			FollowerIds:     []string{},
+			OwnerIds:        []string{},
+			FileIds:         []int64{},
+		})
+		id = st.ID
+	}
+
+	if err != nil {
+		cardOutput.Row(b.URL, "Failed", err)
+		return
+	}
+
+	cardOutput.Row(b.URL, "Success", fmt.Sprintf("Board %s ID: %d %s", kind, id, co.clubhouseAppURL(kind, id)))
+}
+
+// buildBoardIntro is the description of the intro, the board description
+// followed by where the board was and its background
+func buildBoardIntro(b *boardDetails, to *TrelloOptions) string {
+	var s strings.Builder
+
+	if d := strings.TrimSpace(b.Desc); d != "" {
+		s.WriteString(to.CardTitles.expandCardLinks(to.transformBody(d)))
+		s.WriteString("\n\n")
+	} else {
+		s.WriteString("The board had no description.\n\n")
+	}
+
+	s.WriteString("---\n")
+	fmt.Fprintf(&s, "Trello board: [%s](%s)\n", escapeLinkText(b.Name), b.URL)
+	if b.Organization.URL != "" {
+		fmt.Fprintf(&s, "Workspace: [%s](%s)\n", escapeLinkText(b.Organization.DisplayName), b.Organization.URL)
+	}
+
+	if b.Prefs.BackgroundImage != "" {
+		fmt.Fprintf(&s, "Background: [image](%s)\n", b.Prefs.BackgroundImage)
+	} else if b.Prefs.Background != "" {
+		fmt.Fprintf(&s, "Background: %s\n", b.Prefs.Background)
+	}
+
+	return s.String()
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	trello "github.com/jnormington/go-trello"
+)
+
+// trelloCardLinkRegexp matches a trello card url with the optional slug and
+// comment or action suffixes trello adds when copying the link
+var trelloCardLinkRegexp = regexp.MustCompile(`https://trello\.com/c/([A-Za-z0-9]+)(/[^\s)\]>]*)?`)
+
+// cardTitles looks up the names of the cards referenced by their short
+// link, the cards being exported are known up front
+type cardTitles struct {
+	mu    sync.Mutex
+	names map[string]string
+}
+
+func newCardTitles(cards []trello.Card) *cardTitles {
+	ct := &cardTitles{names: map[string]string{}}
+	for _, c := range cards {
+		ct.names[c.ShortLink] = c.Name
+	}
+
+	return ct
+}
+
+// Title returns the name of the card, empty when it can't be read
+// (deleted or on a board the token has no access to)
+func (ct *cardTitles) Title(shortLink string) string {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	if n, ok := ct.names[shortLink]; ok {
+		return n
+	}
+
+	var c struct {
+		Name string `json:"name"`
+	}
+
+	if err := trelloRequest("GET", "/cards/"+shortLink, url.Values{"fields": {"name"}}, &c); err != nil {
+		fmt.Println("Error: Querying the name of the linked card:", shortLink, "ignoring...", err)
+	}
+
+	ct.names[shortLink] = c.Name
+	return c.Name
+}
+
+// expandCardLinks turns the bare trello card urls in s into markdown links with the
+// card name as text, urls already in a markdown link are left alone
+func (ct *cardTitles) expandCardLinks(s string) string {
+	if ct == nil {
+		return s
+	}
+
+	var b strings.Builder
+	var last int
+
+	for _, m := range trelloCardLinkRegexp.FindAllStringSubmatchIndex(s, -1) {
+		start, end := m[0], m[1]
+		if strings.HasSuffix(s[:start], "](") || strings.HasSuffix(s[:start], "[") {
+			continue
+		}
+
+		title := ct.Title(s[m[2]:m[3]])
+		if title == "" {
+			continue
+		}
+
+		b.WriteString(s[last:start])
+		fmt.Fprintf(&b, "[%s](%s)", escapeLinkText(title), s[start:end])
+		last = end
+	}
+
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// escapeLinkText stops brackets in the card name ending the link text early
+func escapeLinkText(s string) string {
+	return strings.NewReplacer("[", "\\[", "]", "\\]").Replace(s)
+}
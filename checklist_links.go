@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+
+	ch "github.com/jnormington/clubhouse-go"
+)
+
+const (
+	checklistLinksBlockedBy = "blocked-by"
+	checklistLinksBlocks    = "blocks"
+	checklistLinksRelatesTo = "relates-to"
+)
+
+// checklistLink is a checklist item referencing another trello card
+// which becomes a story link once both stories exist
+type checklistLink struct {
+	StoryID   int64
+	TargetURL string
+	Task      Task
+	CardName  string
+}
+
+// storyLinker collects the checklist links while the cards are imported
+// as the referenced card may be imported after the card referencing it
+type storyLinker struct {
+	links   []checklistLink
+	stories map[string]int64
+}
+
+// newStoryLinker returns nil when the checklist items are kept as tasks
+func newStoryLinker(mode string) *storyLinker {
+	if mode == "" {
+		return nil
+	}
+
+	return &storyLinker{stories: map[string]int64{}}
+}
+
+// checklistLinkURL returns the trello card url in the checklist item
+func checklistLinkURL(t Task) string {
+	if m := trelloShortURLRegexp.FindStringSubmatch(t.Description); m != nil {
+		return "https://trello.com/c/" + m[1]
+	}
+
+	return ""
+}
+
+// Add records the story of the card and its checklist items referencing cards
+func (sl *storyLinker) Add(storyID int64, card *Card) {
+	if sl == nil {
+		return
+	}
+
+	sl.Known(card.ShortURL, storyID)
+
+	for _, t := range card.Tasks {
+		if u := checklistLinkURL(t); u != "" && u != card.ShortURL {
+			sl.links = append(sl.links, checklistLink{StoryID: storyID, TargetURL: u, Task: t, CardName: card.Name})
+		}
+	}
+}
+
+// Known records the story of a card which wasn't created in this run
+func (sl *storyLinker) Known(url string, storyID int64) {
+	if sl != nil {
+		sl.stories[url] = storyID
+	}
+}
+
+// Link creates the story links, items referencing a card without a story
+// (imported in this run or in the story map) are added back as tasks
+func (sl *storyLinker) Link(opts *ClubhouseOptions) {
+	if sl == nil {
+		return
+	}
+
+	for _, l := range sl.links {
+		target, ok := sl.stories[l.TargetURL]
+		if !ok {
+			target, ok = opts.StoryMap[l.TargetURL]
+		}
+
+		if !ok {
+			t := ch.CreateTask{Complete: l.Task.Completed, Description: taskDescription(l.Task)}
+			if err := clubhouseRequest("POST", fmt.Sprintf("/stories/%d/tasks", l.StoryID), t, nil); err != nil {
+				fmt.Println("Error: Adding checklist item as task for card:", l.CardName, "ignoring...", err)
+			}
+			continue
+		}
+
+		// The story with the checklist is blocked by the story it references
+		link := ch.CreateStoryLink{SubjectID: target, ObjectID: l.StoryID, Verb: "blocks"}
+		switch opts.ChecklistLinks {
+		case checklistLinksBlocks:
+			link = ch.CreateStoryLink{SubjectID: l.StoryID, ObjectID: target, Verb: "blocks"}
+		case checklistLinksRelatesTo:
+			link = ch.CreateStoryLink{SubjectID: l.StoryID, ObjectID: target, Verb: "relates to"}
+		}
+
+		if err := clubhouseRequest("POST", "/story-links", link, nil); err != nil {
+			fmt.Println("Error: Linking stories for checklist item on card:", l.CardName, "ignoring...", err)
+		}
+	}
+}
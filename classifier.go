@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const classifierKeywords = "keywords"
+
+var storyTypes = []string{"feature", "chore", "bug"}
+
+// StoryTypeClassifier decides the story type of a card, false is
+// returned when it can't and the selected story type is used
+type StoryTypeClassifier interface {
+	Classify(card *Card) (string, bool)
+}
+
+// keywordClassifier matches whole words in the name and labels first
+// and then the description, checking the story types in order
+type keywordClassifier struct {
+	Rules    map[string][]string
+	order    []string
+	patterns map[string][]*regexp.Regexp
+}
+
+var defaultClassifierRules = map[string][]string{
+	"bug":   {"bug", "bugs", "error", "crash", "broken", "fix", "defect", "regression", "exception"},
+	"chore": {"chore", "refactor", "cleanup", "upgrade", "maintenance", "dependency", "dependencies", "docs"},
+}
+
+// httpClassifier posts the card to an external endpoint which
+// responds with json containing the story_type
+type httpClassifier struct {
+	URL    string
+	client *http.Client
+}
+
+// NewStoryTypeClassifier returns the classifier for the option which is
+// either keywords or the url of an endpoint, the rules file is optional
+func NewStoryTypeClassifier(option string, rulesFile string) StoryTypeClassifier {
+	switch {
+	case option == "":
+		return nil
+	case option == classifierKeywords:
+		return newKeywordClassifier(rulesFile)
+	case strings.HasPrefix(option, "http://") || strings.HasPrefix(option, "https://"):
+		return &httpClassifier{URL: option, client: &http.Client{Timeout: 30 * time.Second}}
+	}
+
+	fatalConfigf("Unknown story type classifier '%s' expected keywords or an http url", option)
+	return nil
+}
+
+func newKeywordClassifier(rulesFile string) *keywordClassifier {
+	kc := keywordClassifier{Rules: defaultClassifierRules}
+
+	if rulesFile != "" {
+		b, err := ioutil.ReadFile(rulesFile)
+		if err != nil {
+			fatalConfigf("Error opening classifier rules: %s", err)
+		}
+
+		kc.Rules = map[string][]string{}
+		if err := yaml.Unmarshal(b, &kc.Rules); err != nil {
+			fatalConfigf("Error reading classifier rules: %s", err)
+		}
+	}
+
+	kc.patterns = map[string][]*regexp.Regexp{}
+	for _, t := range []string{"bug", "chore", "feature"} {
+		if _, ok := kc.Rules[t]; !ok {
+			continue
+		}
+
+		kc.order = append(kc.order, t)
+		for _, k := range kc.Rules[t] {
+			p := regexp.MustCompile(`\b` + regexp.QuoteMeta(strings.ToLower(k)) + `\b`)
+			kc.patterns[t] = append(kc.patterns[t], p)
+		}
+	}
+
+	return &kc
+}
+
+func (kc *keywordClassifier) Classify(card *Card) (string, bool) {
+	title := strings.ToLower(card.Name + " " + strings.Join(card.Labels, " "))
+	desc := strings.ToLower(card.Desc)
+
+	for _, text := range []string{title, desc} {
+		for _, t := range kc.order {
+			for _, p := range kc.patterns[t] {
+				if p.MatchString(text) {
+					return t, true
+				}
+			}
+		}
+	}
+
+	return "", false
+}
+
+func (hc *httpClassifier) Classify(card *Card) (string, bool) {
+	b, _ := json.Marshal(map[string]interface{}{
+		"name":        card.Name,
+		"description": card.Desc,
+		"labels":      card.Labels,
+	})
+
+	resp, err := hc.client.Post(hc.URL, "application/json", bytes.NewReader(b))
+	if err != nil {
+		fmt.Println("Error: Classifying story type for:", card.Name, "ignoring...", err)
+		return "", false
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		StoryType string `json:"story_type"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil || resp.StatusCode >= 300 {
+		fmt.Println("Error: Classifying story type for:", card.Name, "ignoring... status", resp.StatusCode, err)
+		return "", false
+	}
+
+	if validStoryType(out.StoryType) {
+		return out.StoryType, true
+	}
+
+	return "", false
+}
+
+func validStoryType(t string) bool {
+	for _, s := range storyTypes {
+		if s == t {
+			return true
+		}
+	}
+
+	return false
+}
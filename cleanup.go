@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+
+	ch "github.com/jnormington/clubhouse-go"
+)
+
+// trelloCardIDRegexp matches the trello card id imported stories keep as external id
+var trelloCardIDRegexp = regexp.MustCompile(`^[0-9a-f]{24}$`)
+
+// runCleanupCommand archives, or deletes, the stories of a project imported from
+// trello so a bad import can be wiped before running it again
+func runCleanupCommand(args []string) {
+	fs := flag.NewFlagSet("cleanup", flag.ExitOnError)
+	project := fs.String("project", "", "name or id of the clubhouse project to clean up, asked when not given")
+	del := fs.Bool("delete-imported", false, "delete the imported stories instead of archiving them")
+	dryRun := fs.Bool("dry-run", false, "only list the imported stories")
+	fs.BoolVar(&nonInteractive, "non-interactive", false, "don't ask for confirmation, -project is needed")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: trello-to-clubhouse cleanup [-project name] [-delete-imported] [-dry-run]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	applyTokens()
+
+	co := &ClubhouseOptions{ClubhouseEntry: ch.New(clubHouseToken), ProjectName: *project}
+	co.getProjectsAndPromptUser()
+
+	stories, err := co.ClubhouseEntry.ListStories(co.Project.ID)
+	if err != nil {
+		fatalConfigf("Error listing the stories of %s: %s", co.Project.Name, err)
+	}
+
+	// Stories already archived are only included when deleting
+	imported := importedStories(co, stories, *del)
+	if len(imported) == 0 {
+		fmt.Println("No stories imported from Trello found in", co.Project.Name)
+		return
+	}
+
+	action, status := "Archive", "Archived"
+	if *del {
+		action, status = "Delete", "Deleted"
+	}
+
+	for _, st := range imported {
+		fmt.Printf("\t%d %s\n", st.ID, st.Name)
+	}
+
+	if *dryRun {
+		fmt.Printf("%d stories imported from Trello in %s, nothing was changed\n", len(imported), co.Project.Name)
+		return
+	}
+
+	if !nonInteractive {
+		fmt.Printf("%s these %d stories imported from Trello in %s?\n", action, len(imported), co.Project.Name)
+		for i, o := range yesNoOpts {
+			fmt.Printf("[%d] %s\n", i, o)
+		}
+
+		if promptUserSelectResource() != 0 {
+			abortRun("Stopping user aborted the cleanup")
+		}
+	}
+
+	var failed int
+	for _, st := range imported {
+		err := retryOnHardLimit(fmt.Sprintf("story %d", st.ID), func() (err error) {
+			if *del {
+				return co.ClubhouseEntry.DeleteStory(st.ID)
+			}
+
+			_, err = co.ClubhouseEntry.UpdateStory(ch.UpdateStory{Archived: true, Name: st.Name}, st.ID)
+			return err
+		})
+
+		if err != nil {
+			failed++
+			fmt.Println("Error:", action, "story:", st.ID, st.Name, classifyError(err))
+			continue
+		}
+
+		fmt.Println(status, "story:", st.ID, st.Name)
+	}
+
+	if failed > 0 {
+		fmt.Printf("*** %d of the %d stories couldn't be changed, run the cleanup again ***\n", failed, len(imported))
+		os.Exit(exitFailures)
+	}
+}
+
+// importedStories are the stories with a trello card id as external id or
+// the trello card link in their description or comments
+func importedStories(co *ClubhouseOptions, stories []ch.Story, archived bool) []ch.Story {
+	var imported []ch.Story
+
+	for _, st := range stories {
+		if st.Archived && !archived {
+			continue
+		}
+
+		found := trelloCardIDRegexp.MatchString(st.ExternalID) || findTrelloShortURL(st) != ""
+		if !found && len(st.Comments) == 0 {
+			full, err := co.ClubhouseEntry.GetStory(st.ID)
+			if err != nil {
+				fmt.Println("Error: Querying comments for story:", st.ID, "ignoring...", err)
+				continue
+			}
+			found = findTrelloShortURL(full) != ""
+		}
+
+		if found {
+			imported = append(imported, st)
+		}
+	}
+
+	return imported
+}
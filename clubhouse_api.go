@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+var clubhouseAPIURL = "https://api.clubhouse.io/api/v3"
+
+// clubhouseAPIError is an error response of the Clubhouse api
+type clubhouseAPIError struct {
+	Method string
+	Path   string
+	Status int
+	Body   string
+}
+
+func (e *clubhouseAPIError) Error() string {
+	return fmt.Sprintf("clubhouse api %s %s returned %d: %s", e.Method, e.Path, e.Status, e.Body)
+}
+
+// clubhouseRequest calls the Clubhouse api directly for the endpoints
+// not covered by the clubhouse-go package, body and out are json encoded
+func clubhouseRequest(method string, path string, body interface{}, out interface{}) error {
+	var b []byte
+
+	if body != nil {
+		var err error
+		if b, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, clubhouseAPIURL+path, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Clubhouse-Token", clubHouseToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	rb, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		return classifyError(&clubhouseAPIError{Method: method, Path: path, Status: resp.StatusCode, Body: string(rb)})
+	}
+
+	if out == nil || len(rb) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(rb, out)
+}
+
+// clubhouseCurrentMember is the member the token belongs to
+type clubhouseCurrentMember struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	MentionName string `json:"mention_name"`
+	Workspace   struct {
+		URLSlug string `json:"url_slug"`
+	} `json:"workspace2"`
+}
+
+func getClubhouseCurrentMember() (*clubhouseCurrentMember, error) {
+	var m clubhouseCurrentMember
+	err := clubhouseRequest("GET", "/member", nil, &m)
+
+	return &m, err
+}
@@ -3,18 +3,67 @@ package main
 import (
 	"fmt"
 	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	ch "github.com/jnormington/clubhouse-go"
 )
 
-//ClubhouseOptions stores the options selected by the user
+// ClubhouseOptions stores the options selected by the user
 type ClubhouseOptions struct {
+	ProjectName              string
+	StateName                string
+	LabelMap                 map[string]string
 	Project                  *ch.Project
 	State                    *ch.State
 	ClubhouseEntry           *ch.Clubhouse
 	StoryType                string
 	AddCommentWithTrelloLink bool
 	ImportMember             *ch.Member
+	URLAttachments           string
+	InlineImages             bool
+	ConfirmEvery             int
+	RequestedBy              string
+	RequestedByMember        *ch.Member
+	DefaultOwner             string
+	DefaultOwnerMember       *ch.Member
+	DescriptionOverflow      string
+	MinCreatedAt             time.Time
+	DropCreatedAt            bool
+	Classifier               StoryTypeClassifier
+	TextHook                 TextHook
+	ScanSecrets              string
+	CommentsMode             string
+	DueLocation              *time.Location
+	EpicCards                string
+	MetadataFooter           bool
+	DueComplete              string
+	CoverLabels              map[string]string
+	DoneState                *ch.State
+	WorkspaceSlug            string
+	Annotator                *CardAnnotator
+	Priorities               []string
+	PriorityField            *PriorityField
+	Reconcile                bool
+	StoryMap                 map[string]int64
+	StoryLists               map[string]string
+	StoryMapPath             string
+	Mode                     string
+	OnDuplicate              string
+	MultipleDuplicates       string
+	DryRun                   bool
+	ExternalLink             bool
+	Review                   string
+	States                   []ch.State
+	TemplateCards            string
+	TemplatePattern          *regexp.Regexp
+	ChecklistLinks           string
+	CreatedYearLabels        bool
+	LabelNamespace           string
+	TokenMemberID            string
+	CommentAuthorFallback    bool
 }
 
 type worfklowState struct {
@@ -37,21 +86,109 @@ func (co *ClubhouseOptions) ListMembers() *[]ch.Member {
 
 // SetupClubhouseOptions calls all the functions which consist of questions
 // for building ClubhouseOptions and returns a pointer to ClubhouseOptions instance
-func SetupClubhouseOptions() *ClubhouseOptions {
+func SetupClubhouseOptions(cfg *Config) *ClubhouseOptions {
 	var co ClubhouseOptions
 
 	co.ClubhouseEntry = ch.New(clubHouseToken)
+	co.ProjectName = cfg.Project
+	co.StateName = cfg.State
+	co.URLAttachments = cfg.URLAttachments
+	co.ConfirmEvery = cfg.ConfirmEvery
+	co.RequestedBy = cfg.RequestedBy
+	co.DefaultOwner = cfg.DefaultOwner
+	co.DescriptionOverflow = cfg.DescriptionOverflow
+	co.DropCreatedAt = cfg.DropCreatedAt
+	co.EpicCards = cfg.EpicCards
+	co.MetadataFooter = cfg.MetadataFooter
+	co.DueComplete = cfg.DueComplete
+	co.CoverLabels = parseKeyValues(cfg.CoverLabels)
+	co.Priorities = cfg.PositionPriorities
+	co.Reconcile = cfg.Reconcile
+	co.Mode = cfg.Mode
+	co.OnDuplicate = cfg.OnDuplicate
+	co.MultipleDuplicates = cfg.MultipleDuplicates
+	co.DryRun = cfg.DryRun
+	co.ExternalLink = cfg.ExternalLink
+	co.Review = cfg.Review
+	co.TemplateCards = cfg.TemplateCards
+	co.ChecklistLinks = cfg.ChecklistLinks
+	co.CreatedYearLabels = cfg.CreatedYearLabels
+	co.LabelNamespace = cfg.LabelNamespace
+	co.TemplatePattern = compileTemplatePattern(cfg.TemplatePattern)
+	co.StoryMapPath = cfg.StoryMap
+	if co.Mode == modeUpdate && !co.Reconcile {
+		co.StoryMap, co.StoryLists = loadStoryMap(cfg.StoryMap)
+	}
+	co.Classifier = NewStoryTypeClassifier(cfg.StoryTypeClassifier, cfg.ClassifierRules)
+	co.TextHook = NewTextHook(cfg.TextHook)
+	co.ScanSecrets = cfg.ScanSecrets
+	co.CommentsMode = cfg.Comments
+	if cfg.DueTimezone != "" {
+		co.DueLocation, _ = time.LoadLocation(cfg.DueTimezone)
+	}
+	co.MinCreatedAt, _ = time.Parse(minCreatedAtLayout, cfg.MinCreatedAt)
+	co.StoryType = cfg.StoryType
+	co.AddCommentWithTrelloLink = cfg.TrelloLinkComment
 
+	co.confirmWorkspace(cfg.WorkspaceSlug)
 	co.getProjectsAndPromptUser()
+	if !co.DryRun {
+		co.verifyTokenPermissions()
+	}
 	co.getWorkflowStatesAndPromptUser()
-	co.getMembersAndPromptUser()
+	co.getMembersAndPromptUser(cfg.ImportMember)
+	co.findRequestedByMember(cfg.RequestedByMember)
+	co.findDefaultOwnerMember(cfg.DefaultOwnerMember)
+	co.findPriorityField(cfg.PriorityField)
 	co.promptUserForStoryType()
 	co.promptUserIfAddCommentWithTrelloLink()
 
 	return &co
 }
 
+// confirmWorkspace shows the workspace the token belongs to as tokens are
+// per workspace, when the expected slug is given it must match instead
+func (co *ClubhouseOptions) confirmWorkspace(slug string) {
+	m, err := getClubhouseCurrentMember()
+	if err != nil {
+		fatalConfigf("Error checking the Clubhouse token: %s", err)
+	}
+
+	ws := m.Workspace.URLSlug
+	co.WorkspaceSlug = ws
+	co.TokenMemberID = m.ID
+	if slug != "" {
+		if !strings.EqualFold(slug, ws) {
+			fatalConfigf("Clubhouse token belongs to the workspace '%s' not '%s' stopping", ws, slug)
+		}
+		return
+	}
+
+	fmt.Printf("The Clubhouse token belongs to %s in the workspace '%s' (https://app.clubhouse.io/%s)\n", m.Name, ws, ws)
+	if nonInteractive {
+		return
+	}
+
+	fmt.Println("Is this the workspace you want to import into ?")
+	for i, b := range yesNoOpts {
+		fmt.Printf("[%d] %s\n", i, b)
+	}
+
+	if promptUserSelectResource() != 0 {
+		abortRun("Stopping user aborted as the Clubhouse workspace is wrong")
+	}
+}
+
+// clubhouseAppURL is the link to a story or epic in the clubhouse web app
+func (co *ClubhouseOptions) clubhouseAppURL(kind string, id int64) string {
+	return fmt.Sprintf("https://app.clubhouse.io/%s/%s/%d", co.WorkspaceSlug, kind, id)
+}
+
 func (co *ClubhouseOptions) promptUserIfAddCommentWithTrelloLink() {
+	if nonInteractive {
+		return
+	}
+
 	fmt.Println("Would you like a comment added with the original trello ticket link?")
 	for i, b := range yesNoOpts {
 		fmt.Printf("[%d] %s\n", i, b)
@@ -73,9 +210,59 @@ func (co *ClubhouseOptions) getProjectsAndPromptUser() {
 		log.Fatal(err)
 	}
 
+	if len(projects) == 0 {
+		promptRecovery("No Clubhouse projects found", []string{
+			"the token belongs to a different workspace than the one with the projects",
+			"the workspace has no projects yet, create one in Clubhouse first",
+		}, "Enter a different Clubhouse token")
+
+		fmt.Println("Please paste your Clubhouse token, you can create one in Clubhouse under Settings > API Tokens")
+		clubHouseToken = promptUserForText()
+		co.ClubhouseEntry = ch.New(clubHouseToken)
+		co.confirmWorkspace("")
+		co.getProjectsAndPromptUser()
+		return
+	}
+
+	var names []string
+	for _, p := range projects {
+		names = append(names, p.Name)
+	}
+	dups := duplicateNames(names)
+	teams := co.projectTeams(dups)
+
+	if co.ProjectName != "" {
+		var matches []int
+		for i, p := range projects {
+			if strconv.FormatInt(p.ID, 10) == co.ProjectName {
+				co.Project = &projects[i]
+				return
+			}
+
+			if sameName(p.Name, co.ProjectName) {
+				matches = append(matches, i)
+			}
+		}
+
+		switch len(matches) {
+		case 0:
+			fatalConfigf("Project '%s' not found", co.ProjectName)
+		case 1:
+			co.Project = &projects[matches[0]]
+			return
+		}
+
+		var found []string
+		for _, i := range matches {
+			p := projects[i]
+			found = append(found, choiceName(p.Name, strconv.FormatInt(p.ID, 10), teams[p.TeamID], dups))
+		}
+		fatalAmbiguousName("Project", co.ProjectName, found)
+	}
+
 	fmt.Println("Please select a project by it number to import the cards into")
 	for i, p := range projects {
-		fmt.Printf("[%d] %s\n", i, p.Name)
+		fmt.Printf("[%d] %s\n", i, choiceName(p.Name, strconv.FormatInt(p.ID, 10), teams[p.TeamID], dups))
 	}
 
 	i := promptUserSelectResource()
@@ -86,12 +273,44 @@ func (co *ClubhouseOptions) getProjectsAndPromptUser() {
 	co.Project = &projects[i]
 }
 
-func (co *ClubhouseOptions) getMembersAndPromptUser() {
+// projectTeams names the team of the projects by their workflow,
+// only looked up when there are projects with the same name
+func (co *ClubhouseOptions) projectTeams(dups map[string]bool) map[int64]string {
+	teams := map[int64]string{}
+	if len(dups) == 0 {
+		return teams
+	}
+
+	workflows, err := co.ClubhouseEntry.ListWorkflow()
+	if err != nil {
+		fmt.Println("Error: Querying the workflows to tell the projects apart ignoring...", err)
+		return teams
+	}
+
+	for _, w := range workflows {
+		teams[w.TeamID] = "workflow " + w.Name
+	}
+
+	return teams
+}
+
+func (co *ClubhouseOptions) getMembersAndPromptUser(email string) {
 	members, err := co.ClubhouseEntry.ListMembers()
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	if email != "" {
+		for i, m := range members {
+			if strings.EqualFold(m.Profile.EmailAddress, email) {
+				co.ImportMember = &members[i]
+				return
+			}
+		}
+
+		fatalConfigf("No Clubhouse member found with the email '%s' for the import member", email)
+	}
+
 	fmt.Println("Please select a backup user account if a user is not mapped correctly")
 	for i, u := range members {
 		fmt.Printf("[%d] %s\n", i, u.Profile.Name)
@@ -105,6 +324,36 @@ func (co *ClubhouseOptions) getMembersAndPromptUser() {
 	co.ImportMember = &members[i]
 }
 
+func (co *ClubhouseOptions) findRequestedByMember(email string) {
+	if co.RequestedBy != requestedByFixedMember {
+		return
+	}
+
+	for _, m := range *co.ListMembers() {
+		if m.Profile.EmailAddress == email {
+			co.RequestedByMember = &m
+			return
+		}
+	}
+
+	fatalConfigf("No Clubhouse member found with the email '%s' for requested by", email)
+}
+
+func (co *ClubhouseOptions) findDefaultOwnerMember(email string) {
+	if co.DefaultOwner != defaultOwnerMember {
+		return
+	}
+
+	for _, m := range *co.ListMembers() {
+		if m.Profile.EmailAddress == email {
+			co.DefaultOwnerMember = &m
+			return
+		}
+	}
+
+	fatalConfigf("No Clubhouse member found with the email '%s' for the default owner", email)
+}
+
 func (co *ClubhouseOptions) getWorkflowStatesAndPromptUser() {
 	workflows, err := co.ClubhouseEntry.ListWorkflow()
 	if err != nil {
@@ -120,6 +369,11 @@ func (co *ClubhouseOptions) getWorkflowStatesAndPromptUser() {
 		}
 
 		for sIdx, s := range w.States {
+			if s.Type == "done" && co.DoneState == nil {
+				co.DoneState = &workflows[wIdx].States[sIdx]
+			}
+			co.States = append(co.States, s)
+
 			options = append(options, worfklowState{
 				WorkflowIdx: wIdx,
 				StateIdx:    sIdx,
@@ -128,6 +382,18 @@ func (co *ClubhouseOptions) getWorkflowStatesAndPromptUser() {
 		}
 	}
 
+	if co.StateName != "" {
+		for _, o := range options {
+			s := &workflows[o.WorkflowIdx].States[o.StateIdx]
+			if sameName(s.Name, co.StateName) || strconv.FormatInt(s.ID, 10) == co.StateName {
+				co.State = s
+				return
+			}
+		}
+
+		fatalConfigf("Workflow state '%s' not found for project '%s'", co.StateName, co.Project.Name)
+	}
+
 	for i, o := range options {
 		fmt.Printf("[%d] %s\n", i, o.DisplayText)
 	}
@@ -142,17 +408,39 @@ func (co *ClubhouseOptions) getWorkflowStatesAndPromptUser() {
 }
 
 func (co *ClubhouseOptions) promptUserForStoryType() {
-	types := []string{"feature", "chore", "bug"}
+	if co.StoryType != "" {
+		return
+	}
+
+	if nonInteractive {
+		fatalConfig("Non interactive requires the -story-type or a story_type for the list in the mapping file")
+	}
 
 	fmt.Println("Please select the story type all cards should be imported as")
-	for i, t := range types {
+	if co.Classifier != nil {
+		fmt.Println("The story type classifier is used first, this is for cards it can't classify")
+	}
+
+	for i, t := range storyTypes {
 		fmt.Printf("[%d] %s\n", i, t)
 	}
 
 	i := promptUserSelectResource()
-	if i >= len(types) {
+	if i >= len(storyTypes) {
 		log.Fatal(errOutOfRange)
 	}
 
-	co.StoryType = types[i]
+	co.StoryType = storyTypes[i]
+}
+
+// storyTypeFor returns the classified story type of the card
+// falling back to the selected story type
+func (co *ClubhouseOptions) storyTypeFor(card *Card) string {
+	if co.Classifier != nil {
+		if t, ok := co.Classifier.Classify(card); ok {
+			return t
+		}
+	}
+
+	return co.StoryType
 }
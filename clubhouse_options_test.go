@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// withClubhouseWorkspace answers the current member call with a member of the workspace
+func withClubhouseWorkspace(t *testing.T, slug string) func() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/member" {
+			t.Errorf("unexpected call %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"id":"ch-importer","name":"Importer","workspace2":{"url_slug":"%s"}}`, slug)
+	}))
+
+	saved := clubhouseAPIURL
+	clubhouseAPIURL = srv.URL
+	return func() {
+		clubhouseAPIURL = saved
+		srv.Close()
+	}
+}
+
+func TestConfirmWorkspaceMatch(t *testing.T) {
+	defer withClubhouseWorkspace(t, "acme")()
+
+	cfg := ParseConfig([]string{"-workspace-slug", "ACME"})
+	co := &ClubhouseOptions{}
+	co.confirmWorkspace(cfg.WorkspaceSlug)
+
+	if co.WorkspaceSlug != "acme" || co.TokenMemberID != "ch-importer" {
+		t.Errorf("expected the workspace acme and member ch-importer got '%s' and '%s'", co.WorkspaceSlug, co.TokenMemberID)
+	}
+}
+
+// TestConfirmWorkspaceMismatch runs itself in a process of its own as
+// a slug which doesn't match stops the run
+func TestConfirmWorkspaceMismatch(t *testing.T) {
+	if os.Getenv("TEST_WORKSPACE_MISMATCH") == "1" {
+		defer withClubhouseWorkspace(t, "acme")()
+
+		cfg := ParseConfig([]string{"-workspace-slug", "other"})
+		co := &ClubhouseOptions{}
+		co.confirmWorkspace(cfg.WorkspaceSlug)
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run", "^TestConfirmWorkspaceMismatch$")
+	cmd.Env = append(os.Environ(), "TEST_WORKSPACE_MISMATCH=1")
+	err := cmd.Run()
+
+	e, ok := err.(*exec.ExitError)
+	if !ok || e.ExitCode() != exitConfigError {
+		t.Fatalf("expected exit code %d for a workspace mismatch got %v", exitConfigError, err)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"regexp"
+)
+
+// trelloAttachmentURLRegexp matches the attachment urls trello puts in comments,
+// the download urls with the attachment id and the older s3 urls
+var trelloAttachmentURLRegexp = regexp.MustCompile(
+	`https://(?:trello\.com/1/cards/[0-9a-f]{24}/attachments/([0-9a-f]{24})/download|trello-attachments\.s3\.amazonaws\.com)/[^\s)\]>"]+`)
+
+// rewriteCommentAttachments replaces the attachment urls in the comments with
+// their dropbox links so screenshots in discussions still render, uploaded is
+// keyed by both the attachment id and url
+func rewriteCommentAttachments(comments []Comment, uploaded map[string]string) {
+	if len(uploaded) == 0 {
+		return
+	}
+
+	for i := range comments {
+		comments[i].Text = trelloAttachmentURLRegexp.ReplaceAllStringFunc(comments[i].Text, func(u string) string {
+			link, ok := uploaded[u]
+			if !ok {
+				if m := trelloAttachmentURLRegexp.FindStringSubmatch(u); m[1] != "" {
+					link, ok = uploaded[m[1]]
+				}
+			}
+
+			if !ok {
+				// Attached to another card or skipped by the filters
+				return u
+			}
+
+			return dropboxRawURL(link)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	trello "github.com/jnormington/go-trello"
+)
+
+// commentFilter leaves the noise comments out of the export,
+// the zero value keeps every comment
+type commentFilter struct {
+	SkipBots bool
+	SkipBy   map[string]bool
+	Since    time.Time
+	Skip     *regexp.Regexp
+}
+
+// newCommentFilter builds the filter of the options, since was validated as YYYY-MM-DD
+func newCommentFilter(skipBots bool, skipBy []string, since string, skip string) commentFilter {
+	f := commentFilter{SkipBots: skipBots, SkipBy: map[string]bool{}}
+
+	for _, u := range skipBy {
+		f.SkipBy[strings.ToLower(strings.TrimPrefix(strings.TrimSpace(u), "@"))] = true
+	}
+
+	f.Since, _ = time.Parse(minCreatedAtLayout, since)
+
+	if skip != "" {
+		f.Skip = regexp.MustCompile(skip)
+	}
+
+	return f
+}
+
+// Keeps returns false for a comment by a bot (butlerbot and integrations have
+// usernames ending in bot) or a skipped member, older than since or matching skip
+func (f commentFilter) Keeps(a *trello.Action, createdAt *time.Time) bool {
+	username := strings.ToLower(a.MemberCreator.Username)
+
+	switch {
+	case f.SkipBots && strings.HasSuffix(username, "bot"):
+		return false
+	case f.SkipBy[username]:
+		return false
+	case !f.Since.IsZero() && createdAt != nil && createdAt.Before(f.Since):
+		return false
+	case f.Skip != nil && f.Skip.MatchString(a.Data.Text):
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,584 @@
+package main
+
+import (
+	"flag"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const minCreatedAtLayout = "2006-01-02"
+
+// Config stores the options supplied on the command line
+// which are used when building TrelloOptions and ClubhouseOptions
+type Config struct {
+	ConfigFile             string
+	Profile                string
+	Workspace              string
+	Board                  string
+	List                   string
+	WorkspaceSlug          string
+	Project                string
+	State                  string
+	Mapping                string
+	Report                 string
+	Verify                 bool
+	FileNamePolicy         string
+	AttachmentTypes        stringList
+	ExcludeAttachmentTypes stringList
+	URLAttachments         string
+	InlineImages           bool
+	AttachmentBackend      string
+	MaxAttachmentSize      string
+	ConfirmEvery           int
+	Concurrency            int
+	ConvertEmoji           bool
+	ConvertHTML            bool
+	Output                 string
+	RequestedBy            string
+	RequestedByMember      string
+	DefaultOwner           string
+	DefaultOwnerMember     string
+	DescriptionOverflow    string
+	MinCreatedAt           string
+	DropCreatedAt          bool
+	StoryTypeClassifier    string
+	ClassifierRules        string
+	EpicCards              string
+	MetadataFooter         bool
+	DueComplete            string
+	CoverLabels            stringList
+	DateLayouts            stringList
+	InviteMissing          bool
+	AnnotateCards          string
+	MigratedLabel          string
+	ArchiveSourceCards     bool
+	PositionPriorities     stringList
+	PriorityField          string
+	Reconcile              bool
+	Mode                   string
+	StoryMap               string
+	OnDuplicate            string
+	MultipleDuplicates     string
+	DryRun                 bool
+	ExternalLink           bool
+	Review                 string
+	TemplateCards          string
+	TemplatePattern        string
+	FoldNames              bool
+	ChecklistLinks         string
+	FallbackCreator        string
+	DropboxPathTemplate    string
+	AttachmentManifest     bool
+	ColorLabels            string
+	DescriptionChecklists  string
+	ExpandCardLinks        bool
+	NonInteractive         bool
+	MigrateAttachments     bool
+	StoryType              string
+	TrelloLinkComment      bool
+	ImportMember           string
+	Daemon                 bool
+	Interval               time.Duration
+	BoardIntro             string
+	PickCards              bool
+	OnlyMembers            stringList
+	Sample                 int
+	Limit                  int
+	SaveExport             string
+	FromExport             string
+	DeleteSandbox          string
+	LabelNamespace         string
+	TextHook               string
+	ScanSecrets            string
+	EncryptAttachments     bool
+	AttachmentKeyFile      string
+	LinkAudience           string
+	LinkExpiry             string
+	LinkPassword           string
+	SkipBotComments        bool
+	SkipCommentsBy         stringList
+	CommentsSince          string
+	SkipComments           string
+	Comments               string
+	CleanTrelloMarkup      bool
+	DueTimezone            string
+	Card                   string
+	CreatedYearLabels      bool
+	DropboxQuota           string
+	HTTPRecord             string
+	HTTPReplay             string
+}
+
+// stringList is a flag.Value for comma separated values
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(v string) error {
+	for _, i := range strings.Split(v, ",") {
+		if i = strings.TrimSpace(i); i != "" {
+			*s = append(*s, i)
+		}
+	}
+
+	return nil
+}
+
+// ParseConfig parses the command line arguments into a Config
+func ParseConfig(args []string) *Config {
+	var c Config
+
+	fs := flag.NewFlagSet("trello-to-clubhouse", flag.ExitOnError)
+	fs.StringVar(&c.ConfigFile, "config", defaultConfigFile, "path to the config file")
+	fs.StringVar(&c.Profile, "profile", "", "name of the profile in the config file to use")
+	fs.StringVar(&c.Workspace, "workspace", "", "name or id of the trello workspace to list boards from")
+	fs.StringVar(&c.Board, "board", "", "name or id of the trello board, skips the board question")
+	fs.StringVar(&c.List, "list", "", "name or id of the trello list, skips the list question")
+	fs.StringVar(&c.Project, "project", "", "name or id of the clubhouse project, skips the project question")
+	fs.StringVar(&c.State, "state", "", "name or id of the clubhouse workflow state, skips the workflow state question")
+	fs.StringVar(&c.Mapping, "mapping", "", "path to a yaml mapping file of the board lists, labels and members")
+	fs.StringVar(&c.WorkspaceSlug, "workspace-slug", "", "slug of the clubhouse workspace the token must belong to, skips the workspace question")
+	fs.StringVar(&c.Report, "report", defaultReportFile, "path the json migration report is written to")
+	fs.BoolVar(&c.Verify, "verify", false, "verify every created story against its card after the import")
+	fs.StringVar(&c.FileNamePolicy, "filename-policy", fileNamePolicyUnicode,
+		"how attachment file names are made safe: unicode, ascii or none")
+	fs.Var(&c.AttachmentTypes, "attachment-types",
+		"comma separated mime types of attachments to migrate e.g. image/*,application/pdf")
+	fs.Var(&c.ExcludeAttachmentTypes, "exclude-attachment-types",
+		"comma separated mime types of attachments to skip")
+	fs.StringVar(&c.URLAttachments, "url-attachments", urlAttachmentsLinkedFile,
+		"how attachments which are only a url are imported: linked-file or description")
+	fs.BoolVar(&c.InlineImages, "inline-images", false,
+		"show image attachments in the story description as well as the linked files")
+	fs.StringVar(&c.AttachmentBackend, "attachment-backend", attachmentBackendDropbox,
+		"where the attachments are uploaded to: dropbox")
+	fs.StringVar(&c.MaxAttachmentSize, "max-attachment-size", "",
+		"skip the attachments larger than this size e.g. 25MB, all sizes are migrated when not given")
+	fs.IntVar(&c.ConfirmEvery, "confirm-every", 0,
+		"pause after every N stories and ask whether to continue importing")
+	fs.IntVar(&c.Concurrency, "concurrency", 4,
+		"number of cards exported and attachments uploaded to dropbox at the same time")
+	fs.BoolVar(&c.ConvertEmoji, "convert-emoji", true,
+		"convert :shortcode: emoji in names, descriptions and comments to unicode emoji")
+	fs.StringVar(&c.Output, "output", outputAuto,
+		"how the card results are printed: table (sized to the terminal), json lines or csv, json when stdout isn't a terminal")
+	fs.BoolVar(&c.ConvertHTML, "convert-html", true,
+		"convert html pasted into descriptions and comments to markdown")
+	fs.BoolVar(&c.CleanTrelloMarkup, "clean-trello-markup", true,
+		"turn trello smart links and attachment previews in descriptions and comments into plain links")
+	fs.StringVar(&c.RequestedBy, "requested-by", requestedByCreator,
+		"who the story is requested by: creator, first-owner, import-member or member")
+	fs.StringVar(&c.RequestedByMember, "requested-by-member", "",
+		"email of the clubhouse member used when requested-by is member")
+	fs.StringVar(&c.DefaultOwner, "default-owner", defaultOwnerNone,
+		"who owns the stories of cards without members: none, creator or member")
+	fs.StringVar(&c.DefaultOwnerMember, "default-owner-member", "",
+		"email of the clubhouse member used when default-owner is member")
+	fs.StringVar(&c.DescriptionOverflow, "description-overflow", descriptionOverflowComments,
+		"what happens to descriptions over the clubhouse limit: comments or truncate")
+	fs.StringVar(&c.StoryTypeClassifier, "story-type-classifier", "",
+		"classify the story type of each card using keywords or an http endpoint url")
+	fs.StringVar(&c.ClassifierRules, "classifier-rules", "",
+		"yaml file of story type to keywords used by the keywords classifier")
+	fs.StringVar(&c.EpicCards, "epic-cards", "",
+		"convert cards into epics with a story per checklist item: all or the label of the cards to convert")
+	fs.BoolVar(&c.MetadataFooter, "metadata-footer", false,
+		"add the trello stickers and badges to the story description footer")
+	fs.StringVar(&c.DueComplete, "due-complete", dueCompleteKeep,
+		"what happens to due dates marked complete: keep, clear, label or done-state")
+	fs.Var(&c.CoverLabels, "cover-labels",
+		"comma separated card cover color=label pairs e.g. red=urgent,green=ready")
+	fs.Var(&c.DateLayouts, "date-layouts",
+		"comma separated go time layouts tried before the built in ones when parsing trello dates")
+	fs.BoolVar(&c.InviteMissing, "invite-missing", false,
+		"send clubhouse invitations to active trello members with an email in the user mapping but no clubhouse member")
+	fs.StringVar(&c.AnnotateCards, "annotate-cards", "",
+		"add the clubhouse link to each migrated trello card as a comment or attachment")
+	fs.StringVar(&c.MigratedLabel, "migrated-label", "",
+		"label added to each migrated trello card, created on the board when missing")
+	fs.BoolVar(&c.ArchiveSourceCards, "archive-source-cards", false,
+		"archive each trello card once its story is confirmed created in clubhouse")
+	fs.Var(&c.PositionPriorities, "position-priorities",
+		"comma separated priorities the card positions in the list are bucketed into, top first e.g. P0,P1,P2,P3")
+	fs.StringVar(&c.PriorityField, "priority-field", "",
+		"clubhouse custom field the position priority is written to instead of a label")
+	fs.BoolVar(&c.Reconcile, "reconcile", false,
+		"match existing stories to cards by the trello url in their description or comments and skip those cards")
+	fs.StringVar(&c.Mode, "mode", modeCreate,
+		"create new stories or update the stories already mapped to the cards: create or update")
+	fs.StringVar(&c.StoryMap, "story-map", storyMapFile,
+		"path of the card to story mapping csv used by the update mode")
+	fs.StringVar(&c.OnDuplicate, "on-duplicate", "",
+		"what happens to an existing story for the card: skip, archive, delete or update (default archive, skip with -reconcile)")
+	fs.StringVar(&c.MultipleDuplicates, "multiple-duplicates", "",
+		"which story -on-duplicate applies to when several have the card name: ask, newest, oldest or all (default ask, all with -non-interactive)")
+	fs.BoolVar(&c.DryRun, "dry-run", false,
+		"show what would be imported and how existing stories differ without changing anything")
+	fs.BoolVar(&c.ExternalLink, "external-link", false,
+		"add the trello card url as an external link of the story shown in the clubhouse sidebar")
+	fs.StringVar(&c.Review, "review", "",
+		"review each story before it is created: all or invalid (only the stories with problems)")
+	fs.StringVar(&c.TemplateCards, "template-cards", templateCardsSkip,
+		"what happens to template cards: skip, import as stories or story-template")
+	fs.StringVar(&c.TemplatePattern, "template-pattern", defaultTemplatePattern,
+		"regular expression for card names treated as templates, empty only uses trello card templates")
+	fs.BoolVar(&c.FoldNames, "fold-names", false,
+		"ignore accents and case when matching board, list, label, member, project and state names")
+	fs.StringVar(&c.ChecklistLinks, "checklist-links", "",
+		"turn checklist items with a trello card url into story links: blocked-by, blocks or relates-to")
+	fs.StringVar(&c.FallbackCreator, "fallback-creator", fallbackCreatorEarliestAction,
+		"who cards without a create action are attributed to: earliest-action, board-admin or none")
+	fs.StringVar(&c.DropboxPathTemplate, "dropbox-path-template", defaultDropboxPathTemplate,
+		"go template for the dropbox path of uploaded attachments using .BoardName .ListName .CardName .BoardID .ListID .CardID .ShortLink .Index and .FileName")
+	fs.BoolVar(&c.AttachmentManifest, "attachment-manifest", false,
+		"write a manifest.json next to the uploaded attachments of each card tracing them back to trello")
+	fs.StringVar(&c.ColorLabels, "color-labels", colorLabelsColor,
+		"what happens to trello labels with a color but no name: color (named trello-<color>) or skip")
+	fs.StringVar(&c.DescriptionChecklists, "description-checklists", descriptionChecklistsInline,
+		"what happens to - [ ] markdown checklists in descriptions: inline or tasks")
+	fs.BoolVar(&c.ExpandCardLinks, "expand-card-links", false,
+		"turn trello card urls in descriptions and comments into links with the card name as text")
+	fs.BoolVar(&c.NonInteractive, "non-interactive", false,
+		"never ask a question, fail with the flag to give instead e.g. when running as a job")
+	fs.BoolVar(&c.MigrateAttachments, "migrate-attachments", false,
+		"migrate the attachments to dropbox, used instead of the question with -non-interactive")
+	fs.StringVar(&c.StoryType, "story-type", "",
+		"story type cards are imported as: feature, bug or chore, skips the story type question")
+	fs.BoolVar(&c.TrelloLinkComment, "trello-link-comment", false,
+		"add a comment with the trello card link, used instead of the question with -non-interactive")
+	fs.StringVar(&c.ImportMember, "import-member", "",
+		"email of the clubhouse member used when a user isn't mapped, skips the backup user question")
+	fs.BoolVar(&c.Daemon, "daemon", false,
+		"keep running an incremental sync of the list every -interval, requires -mode=update")
+	fs.DurationVar(&c.Interval, "interval", 15*time.Minute,
+		"time between the syncs of the -daemon e.g. 15m or 1h")
+	fs.StringVar(&c.BoardIntro, "board-intro", "",
+		"create an introductory story or epic with the board description, background and links: story or epic")
+	fs.BoolVar(&c.PickCards, "pick-cards", false,
+		"search and select the cards of the list to migrate instead of migrating them all")
+	fs.StringVar(&c.Card, "card", "",
+		"url of the one trello card to migrate, its board and list are used e.g. to fix a story after the migration")
+	fs.Var(&c.OnlyMembers, "only-member",
+		"only migrate the cards assigned to these trello members, comma separated usernames")
+	fs.IntVar(&c.Sample, "sample", 0,
+		"only migrate every nth card of the list for a pilot run e.g. 25")
+	fs.IntVar(&c.Limit, "limit", 0,
+		"migrate at most this many cards for a pilot run")
+	fs.StringVar(&c.SaveExport, "save-export", "",
+		"save the exported cards to this json lines file so promote can import them without exporting again")
+	fs.StringVar(&c.FromExport, "from-export", "",
+		"import the cards saved by -save-export instead of exporting them again, promote defaults it to "+defaultExportFile)
+	fs.StringVar(&c.DeleteSandbox, "delete-sandbox", "",
+		"migration report of the sandbox run whose stories are deleted once the import succeeds")
+	fs.StringVar(&c.LabelNamespace, "label-namespace", "",
+		"prefix of every label created in clubhouse e.g. trello/")
+	fs.StringVar(&c.TextHook, "text-hook", "",
+		"command or http url each card's name, description and comments are passed through before the import")
+	fs.StringVar(&c.ScanSecrets, "scan-secrets", scanSecretsOff,
+		"scan the cards for possible secrets and personal data: off, report flags them, block doesn't import the cards")
+	fs.BoolVar(&c.EncryptAttachments, "encrypt-attachments", false,
+		"encrypt the attachments with the -attachment-key-file before uploading them to dropbox")
+	fs.StringVar(&c.AttachmentKeyFile, "attachment-key-file", "",
+		"file with the key the attachments are encrypted with")
+	fs.StringVar(&c.LinkAudience, "link-audience", "",
+		"who can open the dropbox shared links of the attachments: public, team or no_one")
+	fs.StringVar(&c.LinkExpiry, "link-expiry", "",
+		"when the dropbox shared links expire, a date (YYYY-MM-DD) or a duration after the migration e.g. 2160h")
+	fs.StringVar(&c.LinkPassword, "link-password", "",
+		"password needed to open the dropbox shared links, better set with TRELLO_TO_CLUBHOUSE_LINK_PASSWORD")
+	fs.BoolVar(&c.SkipBotComments, "skip-bot-comments", false,
+		"leave out the comments of bots such as butler and integrations")
+	fs.Var(&c.SkipCommentsBy, "skip-comments-by",
+		"comma separated trello usernames whose comments are left out")
+	fs.StringVar(&c.CommentsSince, "comments-since", "",
+		"leave out the comments older than this date (YYYY-MM-DD)")
+	fs.StringVar(&c.SkipComments, "skip-comments", "",
+		"leave out the comments matching this regular expression")
+	fs.StringVar(&c.Comments, "comments", commentsEach,
+		"how the card comments are imported: each as its own comment or digest merged into one")
+	fs.BoolVar(&c.CreatedYearLabels, "created-year-labels", false,
+		"label each story with the year its card was created e.g. created-2019")
+	fs.StringVar(&c.DropboxQuota, "dropbox-quota", dropboxQuotaWarn,
+		"what happens when the attachments need more than the dropbox space left: warn, abort or off")
+	fs.StringVar(&c.HTTPRecord, "http-record", "",
+		"record every trello, clubhouse and dropbox http call of the run to this json lines file")
+	fs.StringVar(&c.HTTPReplay, "http-replay", "",
+		"replay the http calls recorded with -http-record instead of calling the apis")
+	fs.StringVar(&c.MinCreatedAt, "min-created-at", "",
+		"earliest created at date (YYYY-MM-DD) sent to clubhouse, older dates are clamped to it")
+	fs.StringVar(&c.DueTimezone, "due-timezone", "",
+		"timezone the trello due times are converted to before keeping only their date e.g. America/New_York or Local")
+	fs.BoolVar(&c.DropCreatedAt, "drop-created-at", false,
+		"don't send the trello created dates, they are added to the description and comments instead")
+
+	fs.Parse(args)
+
+	applyEnvTokens()
+	applyEnvSettings(fs)
+
+	var explicit bool
+	fs.Visit(func(f *flag.Flag) { explicit = explicit || f.Name == "config" })
+	applyConfigFile(fs, c.ConfigFile, c.Profile, explicit)
+
+	c.validate()
+	foldNames = c.FoldNames
+	nonInteractive = c.NonInteractive
+
+	return &c
+}
+
+// parseKeyValues turns key=value items into a map
+func parseKeyValues(items []string) map[string]string {
+	m := map[string]string{}
+
+	for _, i := range items {
+		kv := strings.SplitN(i, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			fatalConfigf("Expected key=value but got '%s'", i)
+		}
+
+		m[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+
+	return m
+}
+
+func (c *Config) validate() {
+	parseKeyValues(c.CoverLabels)
+
+	if c.Concurrency < 1 {
+		fatalConfig("Concurrency must be at least 1")
+	}
+
+	if c.Sample < 0 || c.Limit < 0 {
+		fatalConfig("Sample and limit can't be negative")
+	}
+
+	if _, ok := fileNameSanitizers[c.FileNamePolicy]; !ok {
+		fatalConfigf("Unknown filename policy '%s' expected unicode, ascii or none", c.FileNamePolicy)
+	}
+
+	switch c.RequestedBy {
+	case requestedByCreator, requestedByFirstOwner, requestedByImportMember:
+	case requestedByFixedMember:
+		if c.RequestedByMember == "" {
+			fatalConfig("Requested by member requires the -requested-by-member email")
+		}
+	default:
+		fatalConfigf("Unknown requested by '%s' expected creator, first-owner, import-member or member", c.RequestedBy)
+	}
+
+	switch c.DefaultOwner {
+	case defaultOwnerNone, defaultOwnerCreator:
+	case defaultOwnerMember:
+		if c.DefaultOwnerMember == "" {
+			fatalConfig("Default owner member requires the -default-owner-member email")
+		}
+	default:
+		fatalConfigf("Unknown default owner '%s' expected none, creator or member", c.DefaultOwner)
+	}
+
+	if c.MinCreatedAt != "" {
+		if _, err := time.Parse(minCreatedAtLayout, c.MinCreatedAt); err != nil {
+			fatalConfigf("Invalid min created at '%s' expected YYYY-MM-DD", c.MinCreatedAt)
+		}
+	}
+
+	switch c.DueComplete {
+	case dueCompleteKeep, dueCompleteClear, dueCompleteLabel, dueCompleteDoneState:
+	default:
+		fatalConfigf("Unknown due complete '%s' expected keep, clear, label or done-state", c.DueComplete)
+	}
+
+	if c.PriorityField != "" && len(c.PositionPriorities) == 0 {
+		fatalConfig("Priority field requires the -position-priorities")
+	}
+
+	switch c.Mode {
+	case modeCreate:
+	case modeUpdate:
+		if c.EpicCards != "" {
+			fatalConfig("The update mode doesn't support -epic-cards")
+		}
+		if c.OnDuplicate != "" && c.OnDuplicate != onDuplicateUpdate {
+			fatalConfigf("The update mode can't be used with -on-duplicate=%s", c.OnDuplicate)
+		}
+		c.OnDuplicate = onDuplicateUpdate
+	default:
+		fatalConfigf("Unknown mode '%s' expected create or update", c.Mode)
+	}
+
+	switch c.OnDuplicate {
+	case "":
+		c.OnDuplicate = onDuplicateArchive
+		if c.Reconcile {
+			c.OnDuplicate = onDuplicateSkip
+		}
+	case onDuplicateSkip, onDuplicateArchive, onDuplicateDelete, onDuplicateUpdate:
+	default:
+		fatalConfigf("Unknown on duplicate '%s' expected skip, archive, delete or update", c.OnDuplicate)
+	}
+
+	switch c.MultipleDuplicates {
+	case "":
+		c.MultipleDuplicates = multipleDuplicatesAsk
+		if c.NonInteractive || c.Daemon {
+			c.MultipleDuplicates = multipleDuplicatesAll
+		}
+	case multipleDuplicatesAsk:
+		if c.NonInteractive || c.Daemon {
+			fatalConfig("Asking about multiple duplicates can't be used with -non-interactive or the daemon, use newest, oldest or all")
+		}
+	case multipleDuplicatesNewest, multipleDuplicatesOldest, multipleDuplicatesAll:
+	default:
+		fatalConfigf("Unknown multiple duplicates '%s' expected ask, newest, oldest or all", c.MultipleDuplicates)
+	}
+
+	switch c.Review {
+	case "", reviewAll, reviewInvalid:
+	default:
+		fatalConfigf("Unknown review '%s' expected all or invalid", c.Review)
+	}
+
+	if c.Daemon {
+		if c.Mode != modeUpdate {
+			fatalConfig("The daemon requires -mode=update so each sync updates the stories of the previous ones")
+		}
+		if c.DryRun || c.Review != "" || c.ConfirmEvery > 0 || c.PickCards || c.Sample > 0 || c.Limit > 0 || c.Card != "" {
+			fatalConfig("The daemon can't be used with -dry-run, -review, -confirm-every, -pick-cards, -sample, -limit or -card")
+		}
+		if c.Interval < time.Minute {
+			fatalConfigf("The daemon interval must be at least a minute not %s", c.Interval)
+		}
+	}
+
+	if c.StoryType != "" && !validStoryType(c.StoryType) {
+		fatalConfigf("Unknown story type '%s' expected %s", c.StoryType, strings.Join(storyTypes, ", "))
+	}
+
+	if c.NonInteractive {
+		if c.Review != "" || c.ConfirmEvery > 0 || c.PickCards {
+			fatalConfig("Non interactive can't be used with -review, -confirm-every or -pick-cards as they ask questions")
+		}
+		if c.ImportMember == "" {
+			fatalConfig("Non interactive requires the -import-member email")
+		}
+	}
+
+	if c.AttachmentBackend != attachmentBackendDropbox {
+		fatalConfigf("Unknown attachment backend '%s' expected dropbox", c.AttachmentBackend)
+	}
+
+	if _, err := parseByteSize(c.MaxAttachmentSize); err != nil {
+		fatalConfigf("Invalid max attachment size: %s", err)
+	}
+
+	if c.EncryptAttachments && c.AttachmentKeyFile == "" {
+		fatalConfig("Encrypting the attachments requires the -attachment-key-file")
+	}
+
+	switch c.LinkAudience {
+	case "", linkAudiencePublic, linkAudienceTeam, linkAudienceNoOne:
+	default:
+		fatalConfigf("Unknown link audience '%s' expected public, team or no_one", c.LinkAudience)
+	}
+
+	switch c.Comments {
+	case commentsEach, commentsDigest:
+	default:
+		fatalConfigf("Unknown comments '%s' expected each or digest", c.Comments)
+	}
+
+	switch c.ScanSecrets {
+	case scanSecretsOff, scanSecretsReport, scanSecretsBlock:
+	default:
+		fatalConfigf("Unknown scan secrets '%s' expected off, report or block", c.ScanSecrets)
+	}
+
+	if c.HTTPRecord != "" && c.HTTPReplay != "" {
+		fatalConfig("Only one of -http-record and -http-replay can be used")
+	}
+
+	switch c.DropboxQuota {
+	case dropboxQuotaWarn, dropboxQuotaAbort, dropboxQuotaOff:
+	default:
+		fatalConfigf("Unknown dropbox quota '%s' expected warn, abort or off", c.DropboxQuota)
+	}
+
+	switch c.BoardIntro {
+	case "", boardIntroStory, boardIntroEpic:
+	default:
+		fatalConfigf("Unknown board intro '%s' expected story or epic", c.BoardIntro)
+	}
+
+	switch c.TemplateCards {
+	case templateCardsSkip, templateCardsImport, templateCardsStoryTemplate:
+	default:
+		fatalConfigf("Unknown template cards '%s' expected skip, import or story-template", c.TemplateCards)
+	}
+
+	if c.DueTimezone != "" {
+		if _, err := time.LoadLocation(c.DueTimezone); err != nil {
+			fatalConfigf("Invalid due timezone '%s': %s", c.DueTimezone, err)
+		}
+	}
+
+	if c.CommentsSince != "" {
+		if _, err := time.Parse(minCreatedAtLayout, c.CommentsSince); err != nil {
+			fatalConfigf("Invalid comments since '%s' expected YYYY-MM-DD", c.CommentsSince)
+		}
+	}
+
+	if _, err := regexp.Compile(c.SkipComments); err != nil {
+		fatalConfigf("Invalid skip comments pattern '%s': %s", c.SkipComments, err)
+	}
+
+	if _, err := regexp.Compile(c.TemplatePattern); err != nil {
+		fatalConfigf("Invalid template pattern '%s': %s", c.TemplatePattern, err)
+	}
+
+	switch c.ChecklistLinks {
+	case "", checklistLinksBlockedBy, checklistLinksBlocks, checklistLinksRelatesTo:
+	default:
+		fatalConfigf("Unknown checklist links '%s' expected blocked-by, blocks or relates-to", c.ChecklistLinks)
+	}
+
+	switch c.FallbackCreator {
+	case fallbackCreatorEarliestAction, fallbackCreatorBoardAdmin, fallbackCreatorNone:
+	default:
+		fatalConfigf("Unknown fallback creator '%s' expected earliest-action, board-admin or none", c.FallbackCreator)
+	}
+
+	parseDropboxPathTemplate(c.DropboxPathTemplate)
+
+	switch c.AnnotateCards {
+	case "", annotateCardsComment, annotateCardsAttachment:
+	default:
+		fatalConfigf("Unknown annotate cards '%s' expected comment or attachment", c.AnnotateCards)
+	}
+
+	if c.DescriptionOverflow != descriptionOverflowComments && c.DescriptionOverflow != descriptionOverflowTruncate {
+		fatalConfigf("Unknown description overflow '%s' expected comments or truncate", c.DescriptionOverflow)
+	}
+
+	if c.URLAttachments != urlAttachmentsLinkedFile && c.URLAttachments != urlAttachmentsDescription {
+		fatalConfigf("Unknown url attachments option '%s' expected linked-file or description", c.URLAttachments)
+	}
+
+	switch c.Output {
+	case outputAuto, outputTable, outputJSON, outputCSV:
+	default:
+		fatalConfigf("Unknown output '%s' expected table, json or csv", c.Output)
+	}
+
+	if c.ColorLabels != colorLabelsColor && c.ColorLabels != colorLabelsSkip {
+		fatalConfigf("Unknown color labels option '%s' expected color or skip", c.ColorLabels)
+	}
+
+	if c.DescriptionChecklists != descriptionChecklistsInline && c.DescriptionChecklists != descriptionChecklistsTasks {
+		fatalConfigf("Unknown description checklists option '%s' expected inline or tasks", c.DescriptionChecklists)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const defaultConfigFile = "trello-to-clubhouse.yml"
+
+// ConfigFile is the layout of the config file. Settings use the same
+// names as the command line flags, the profile settings override the
+// defaults and any flag given on the command line overrides both.
+type ConfigFile struct {
+	Credentials ConfigCredentials        `yaml:"credentials"`
+	Defaults    map[string]interface{}   `yaml:"defaults"`
+	Profiles    map[string]ConfigProfile `yaml:"profiles"`
+}
+
+// ConfigProfile holds the overrides for a named profile
+type ConfigProfile struct {
+	Credentials ConfigCredentials      `yaml:"credentials"`
+	Settings    map[string]interface{} `yaml:",inline"`
+}
+
+// ConfigCredentials holds tokens shared by all the profiles
+type ConfigCredentials struct {
+	TrelloKey      string `yaml:"trello_key"`
+	TrelloToken    string `yaml:"trello_token"`
+	ClubhouseToken string `yaml:"clubhouse_token"`
+	DropboxToken   string `yaml:"dropbox_token"`
+
+	DropboxRefreshToken string `yaml:"dropbox_refresh_token"`
+	DropboxAppKey       string `yaml:"dropbox_app_key"`
+	DropboxAppSecret    string `yaml:"dropbox_app_secret"`
+}
+
+func loadConfigFile(path string) (*ConfigFile, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cf ConfigFile
+	if err := yaml.Unmarshal(b, &cf); err != nil {
+		return nil, fmt.Errorf("parsing %s: %s", path, err)
+	}
+
+	return &cf, nil
+}
+
+// applyConfigFile sets the flags which weren't given on the command line
+// from the config file defaults and then the selected profile
+func applyConfigFile(fs *flag.FlagSet, path string, profile string, explicit bool) {
+	cf, err := loadConfigFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && !explicit && profile == "" {
+			// No config file is fine unless one was asked for
+			return
+		}
+
+		fatalConfigf("Error loading config file: %s", err)
+	}
+
+	given := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	if profile != "" {
+		p, ok := cf.Profiles[profile]
+		if !ok {
+			fatalConfigf("Profile '%s' not found in config file %s", profile, path)
+		}
+
+		applyConfigSettings(fs, p.Settings, given)
+		p.Credentials.apply()
+
+		// Mark them so the defaults don't override the profile
+		for k := range p.Settings {
+			given[k] = true
+		}
+	}
+
+	applyConfigSettings(fs, cf.Defaults, given)
+	cf.Credentials.apply()
+}
+
+func applyConfigSettings(fs *flag.FlagSet, settings map[string]interface{}, given map[string]bool) {
+	for k, v := range settings {
+		if given[k] {
+			continue
+		}
+
+		f := fs.Lookup(k)
+		if f == nil {
+			fatalConfigf("Unknown setting '%s' in config file", k)
+		}
+
+		// Lists are additive for flags so start from empty
+		if l, ok := f.Value.(*stringList); ok {
+			*l = nil
+		}
+
+		if err := f.Value.Set(configValueString(v)); err != nil {
+			fatalConfigf("Invalid value for '%s' in config file: %s", k, err)
+		}
+	}
+}
+
+func configValueString(v interface{}) string {
+	if l, ok := v.([]interface{}); ok {
+		var s []string
+		for _, i := range l {
+			s = append(s, fmt.Sprint(i))
+		}
+
+		return strings.Join(s, ",")
+	}
+
+	return fmt.Sprint(v)
+}
+
+// apply only replaces tokens which haven't been set yet so the
+// profile credentials are applied before the shared ones
+func (cc ConfigCredentials) apply() {
+	useStoredToken(&trelloKey, cc.TrelloKey)
+	useStoredToken(&trelloToken, cc.TrelloToken)
+	useStoredToken(&clubHouseToken, cc.ClubhouseToken)
+	useStoredToken(&dropboxToken, cc.DropboxToken)
+	useStoredToken(&dropboxRefreshToken, cc.DropboxRefreshToken)
+	useStoredToken(&dropboxAppKey, cc.DropboxAppKey)
+	useStoredToken(&dropboxAppSecret, cc.DropboxAppSecret)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+
+	trello "github.com/jnormington/go-trello"
+)
+
+const (
+	fallbackCreatorEarliestAction = "earliest-action"
+	fallbackCreatorBoardAdmin     = "board-admin"
+	fallbackCreatorNone           = "none"
+)
+
+// fallbackCreator attributes a card without a create action in its history,
+// to the member of its earliest action or the first board admin
+func (t *TrelloOptions) fallbackCreator(actions []trello.Action) string {
+	switch t.FallbackCreator {
+	case fallbackCreatorEarliestAction:
+		// The actions are newest first
+		for i := len(actions) - 1; i >= 0; i-- {
+			if actions[i].IdMemberCreator != "" {
+				return actions[i].IdMemberCreator
+			}
+		}
+		return t.boardAdminID
+	case fallbackCreatorBoardAdmin:
+		return t.boardAdminID
+	}
+
+	return ""
+}
+
+// findBoardAdmin looks up the first admin of the board used as the creator
+// of the cards which have no other member to attribute them to
+func (t *TrelloOptions) findBoardAdmin() {
+	if t.FallbackCreator == fallbackCreatorNone {
+		return
+	}
+
+	var memberships []struct {
+		IDMember   string `json:"idMember"`
+		MemberType string `json:"memberType"`
+	}
+
+	err := trelloRequest("GET", "/boards/"+t.Board.Id+"/memberships", url.Values{"filter": {"admins"}}, &memberships)
+	if err != nil {
+		fmt.Println("Error: Querying the board admins ignoring...", err)
+		return
+	}
+
+	for _, m := range memberships {
+		if m.MemberType == "admin" {
+			t.boardAdminID = m.IDMember
+			return
+		}
+	}
+}
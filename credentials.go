@@ -0,0 +1,200 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	credentialsDir     = "trello-to-clubhouse"
+	credentialsFile    = "credentials.enc"
+	passphraseEnv      = "TRELLO_TO_CLUBHOUSE_PASSPHRASE"
+	credentialsKDFIter = 100000
+	credentialsSaltLen = 16
+)
+
+var errBadPassphrase = errors.New("unable to decrypt credentials, is the passphrase correct ?")
+
+// Credentials holds the tokens captured by the auth command
+type Credentials struct {
+	TrelloKey      string `json:"trello_key"`
+	TrelloToken    string `json:"trello_token"`
+	DropboxToken   string `json:"dropbox_token"`
+	ClubhouseToken string `json:"clubhouse_token"`
+
+	DropboxRefreshToken string `json:"dropbox_refresh_token,omitempty"`
+	DropboxAppKey       string `json:"dropbox_app_key,omitempty"`
+}
+
+func getCredentialsPath() string {
+	d, err := os.UserConfigDir()
+	if err != nil {
+		d, _ = os.Getwd()
+	}
+
+	return filepath.Join(d, credentialsDir, credentialsFile)
+}
+
+func credentialsExist() bool {
+	_, err := os.Stat(getCredentialsPath())
+	return err == nil
+}
+
+func promptPassphrase() string {
+	if p := os.Getenv(passphraseEnv); p != "" {
+		return p
+	}
+
+	fmt.Println("Please enter the passphrase used to encrypt your stored tokens")
+	if fd := int(os.Stdin.Fd()); terminal.IsTerminal(fd) {
+		// The passphrase isn't echoed as it is typed
+		p, err := terminal.ReadPassword(fd)
+		fmt.Println()
+		if err != nil {
+			return ""
+		}
+
+		return string(p)
+	}
+
+	p, err := stdinReader.ReadString('\n')
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimRight(p, "\r\n")
+}
+
+func credentialsKey(passphrase string, salt []byte) []byte {
+	return pbkdf2.Key([]byte(passphrase), salt, credentialsKDFIter, 32, sha256.New)
+}
+
+// loadCredentials decrypts the credentials file with the passphrase.
+// The file layout is salt | nonce | ciphertext
+func loadCredentials(passphrase string) (*Credentials, error) {
+	b, err := ioutil.ReadFile(getCredentialsPath())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) < credentialsSaltLen {
+		return nil, errBadPassphrase
+	}
+
+	salt, b := b[:credentialsSaltLen], b[credentialsSaltLen:]
+	gcm, err := newCredentialsCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) < gcm.NonceSize() {
+		return nil, errBadPassphrase
+	}
+
+	nonce, b := b[:gcm.NonceSize()], b[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, b, nil)
+	if err != nil {
+		return nil, errBadPassphrase
+	}
+
+	var c Credentials
+	if err := json.Unmarshal(plain, &c); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// saveCredentials encrypts the credentials with the passphrase and
+// writes them to the credentials file only readable by the current user
+func saveCredentials(c *Credentials, passphrase string) error {
+	plain, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	salt := make([]byte, credentialsSaltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return err
+	}
+
+	gcm, err := newCredentialsCipher(passphrase, salt)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+
+	out := append(salt, nonce...)
+	out = gcm.Seal(out, nonce, plain, nil)
+
+	p := getCredentialsPath()
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(p, out, 0600)
+}
+
+func newCredentialsCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(credentialsKey(passphrase, salt))
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// applyTokens uses the tokens from the environment and then the stored ones,
+// for the subcommands which don't parse the whole config
+func applyTokens() {
+	applyEnvTokens()
+	applyStoredCredentials()
+}
+
+// applyStoredCredentials loads the stored tokens, if any, and uses them
+// for any token which hasn't already been supplied
+func applyStoredCredentials() {
+	if !credentialsExist() {
+		return
+	}
+
+	c, err := loadCredentials(promptPassphrase())
+	if err != nil {
+		fmt.Println("Error: Loading stored credentials ignoring...", err)
+		return
+	}
+
+	useStoredToken(&trelloKey, c.TrelloKey)
+	useStoredToken(&trelloToken, c.TrelloToken)
+	useStoredToken(&dropboxToken, c.DropboxToken)
+	useStoredToken(&dropboxRefreshToken, c.DropboxRefreshToken)
+	useStoredToken(&dropboxAppKey, c.DropboxAppKey)
+	useStoredToken(&clubHouseToken, c.ClubhouseToken)
+}
+
+func useStoredToken(token *string, stored string) {
+	if stored == "" {
+		return
+	}
+
+	if tokenMissing(*token) {
+		*token = stored
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jnormington/go-trello"
+)
+
+const (
+	syncLockSuffix = ".lock"
+
+	// syncClockSkew is taken off the start of a sync so cards changed
+	// while it ran (or by a clock ahead of ours) are synced again
+	syncClockSkew = time.Minute
+
+	// syncLockStale is how long a lock file isn't refreshed before it is taken
+	// as left behind by a daemon which was killed
+	syncLockStale = 10 * time.Minute
+)
+
+// runDaemon keeps syncing the list into clubhouse every interval until stopped,
+// the questions are only asked before the first sync and the syncs after it only
+// export the cards without a story or with trello activity since the previous one
+func runDaemon(cfg *Config, to *TrelloOptions, co *ClubhouseOptions, um *UserMap) {
+	lock := cfg.StoryMap + syncLockSuffix
+	lockSync(lock)
+	defer os.Remove(lock)
+
+	done := make(chan struct{})
+	defer close(done)
+	go refreshSyncLock(lock, done)
+
+	log.Printf("Syncing every %s, stop with Ctrl+C or SIGTERM", cfg.Interval)
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	var since time.Time
+	for n := 1; ; n++ {
+		started := time.Now()
+		changed, failed := syncChangedCards(cfg, to, co, um, since)
+
+		// Failed cards are retried by the next sync even when unchanged
+		if failed == 0 {
+			since = started.Add(-syncClockSkew)
+		}
+
+		took := time.Since(started).Round(time.Second)
+		detail := fmt.Sprintf("sync %d took %s, %d changed cards, %d failed", n, took, changed, failed)
+		log.Printf("Finished %s", detail)
+		cardOutput.Event("sync_finished", "", detail)
+
+		if took > cfg.Interval {
+			// The ticks missed while syncing are dropped so syncs never overlap
+			log.Printf("Sync %d took longer than the %s interval, starting the next straight away", n, cfg.Interval)
+		}
+
+		if stopRequested() {
+			log.Printf("Stopped after %d syncs", n)
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stopped:
+			log.Printf("Stopped after %d syncs", n)
+			return
+		}
+	}
+}
+
+// lockSync stops a second daemon syncing into the same story map,
+// they would both create stories for the new cards. A lock left by
+// a daemon which crashed or was killed is taken over
+func lockSync(path string) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if os.IsExist(err) && staleSyncLock(path) {
+		log.Printf("Taking over the sync lock file %s left by a sync which is no longer running", path)
+		if err = os.Remove(path); err == nil {
+			f, err = os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		}
+	}
+	if os.IsExist(err) {
+		fatalConfigf("Another sync is running with the lock file %s, remove it if no sync is running", path)
+	}
+	if err != nil {
+		fatalConfigf("Error creating the sync lock file: %s", err)
+	}
+
+	fmt.Fprintf(f, "%d\n", os.Getpid())
+	f.Close()
+}
+
+// staleSyncLock returns true when the lock wasn't refreshed within syncLockStale
+// or its process is gone. A restarted container gets the same pid as the crashed
+// one so our own pid is gone too
+func staleSyncLock(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	if time.Since(info.ModTime()) > syncLockStale {
+		return true
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		// Still being written by the other sync
+		return false
+	}
+
+	return pid == os.Getpid() || !processRunning(pid)
+}
+
+func processRunning(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	// Finding the process on windows already checks it is running,
+	// elsewhere signal 0 checks without sending anything
+	if runtime.GOOS == "windows" {
+		return true
+	}
+
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
+
+// refreshSyncLock touches the lock file until done so it never looks stale
+// while the daemon runs, however long a sync takes
+func refreshSyncLock(path string, done <-chan struct{}) {
+	t := time.NewTicker(syncLockStale / 5)
+	defer t.Stop()
+
+	for {
+		select {
+		case now := <-t.C:
+			os.Chtimes(path, now, now)
+		case <-done:
+			return
+		}
+	}
+}
+
+// syncChangedCards imports the changed cards returning how many there were and failed
+func syncChangedCards(cfg *Config, to *TrelloOptions, co *ClubhouseOptions, um *UserMap, since time.Time) (int, int) {
+	all := to.getCards()
+	c := changedCards(all, co.StoryMap, since)
+	if len(c) == 0 {
+		return 0, 0
+	}
+
+	if cfg.ExpandCardLinks {
+		to.CardTitles = newCardTitles(all)
+	}
+	precreateLabels(c, to, co)
+
+	// Each sync writes its own report and uploads over the files of the previous syncs
+	report = newReport()
+	to.DropboxPaths.reset()
+	report.Spill(cfg.Report)
+	failed := ImportCardsIntoClubhouse(ExportCards(&c, to), len(c), co, um)
+	report.Write(cfg.Report)
+	report.PrintDataLoss()
+
+	return len(c), failed
+}
+
+// changedCards keeps the cards without a story and the ones with activity after since,
+// all of them when since is zero
+func changedCards(cards []trello.Card, storyMap map[string]int64, since time.Time) []trello.Card {
+	if since.IsZero() {
+		return cards
+	}
+
+	var changed []trello.Card
+	for _, c := range cards {
+		_, mapped := storyMap[c.ShortUrl]
+		a, err := time.Parse(time.RFC3339, c.DateLastActivity)
+
+		if !mapped || err != nil || a.After(since) {
+			changed = append(changed, c)
+		}
+	}
+
+	return changed
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStaleSyncLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name  string
+		pid   int
+		age   time.Duration
+		stale bool
+	}{
+		{"running", os.Getppid(), 0, false},
+		{"our pid after a restart", os.Getpid(), 0, true},
+		{"not refreshed", os.Getppid(), syncLockStale + time.Minute, true},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(dir, "storyMappingTtoC.csv.lock")
+		if err := ioutil.WriteFile(path, []byte(fmt.Sprintf("%d\n", tt.pid)), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		mod := time.Now().Add(-tt.age)
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := staleSyncLock(path); got != tt.stale {
+			t.Errorf("%s: stale = %t expected %t", tt.name, got, tt.stale)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import "fmt"
+
+// What couldn't be migrated as it was in trello, in the order they are summarized
+const (
+	lossFailedCards    = "Cards which failed to import"
+	lossMissingActions = "Cards without a create action (creator and created date were guessed)"
+	lossDates          = "Dates which couldn't be parsed (left out)"
+	lossMembers        = "Trello members without a Clubhouse member (attributed to the import member)"
+	lossAttachments    = "Attachments with a problem (not uploaded or not verified)"
+	lossLinks          = "Linked files which couldn't be added to the story"
+	lossDescriptions   = "Descriptions truncated"
+	lossSensitive      = "Cards with possible secrets or personal data (blocked with -scan-secrets=block)"
+)
+
+var lossCategories = []string{
+	lossFailedCards, lossMissingActions, lossDates, lossMembers,
+	lossAttachments, lossLinks, lossDescriptions, lossSensitive,
+}
+
+// AddDataLoss records something of the card which needs a manual follow-up,
+// unlike the card reports they are kept in memory for the end of run summary
+func (r *Report) AddDataLoss(category string, url string, name string, detail string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.addDataLoss(category, url, name, detail)
+}
+
+// addDataLoss is AddDataLoss for callers holding the lock
+func (r *Report) addDataLoss(category string, url string, name string, detail string) {
+	if r.DataLoss == nil {
+		r.DataLoss = map[string][]string{}
+	}
+
+	e := detail
+	if url != "" {
+		e = fmt.Sprintf("%s (%s): %s", name, url, detail)
+	}
+
+	r.DataLoss[category] = append(r.DataLoss[category], e)
+}
+
+// PrintDataLoss lists everything which couldn't be migrated so
+// the manual follow-up needed is known at the end of the run
+func (r *Report) PrintDataLoss() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.DataLoss) == 0 {
+		fmt.Println("Nothing was lost in the migration, no manual follow-up needed")
+		return
+	}
+
+	fmt.Println("****** NEEDS FOLLOW-UP ******")
+	fmt.Println("The following couldn't be migrated as it was in Trello")
+
+	for _, c := range lossCategories {
+		entries := r.DataLoss[c]
+		if len(entries) == 0 {
+			continue
+		}
+
+		fmt.Printf("\n%s (%d)\n", c, len(entries))
+		for _, e := range entries {
+			fmt.Println("\t-", e)
+		}
+
+		if c == lossFailedCards {
+			r.printFailureCauses()
+		}
+	}
+
+	fmt.Println()
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tj/go-dropbox"
+)
+
+// dropboxRenewMargin renews the access token this long before it expires
+// so an upload started just before doesn't fail part way through
+const dropboxRenewMargin = 5 * time.Minute
+
+var (
+	dropboxRefreshToken string
+	dropboxAppKey       string
+	dropboxAppSecret    string
+
+	dropboxSession dropboxAccess
+)
+
+// dropboxAccess keeps the short lived access token renewed with the refresh token
+type dropboxAccess struct {
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// dropboxConfig returns the config with a current access token, without
+// a refresh token the supplied access token is used as it is
+func dropboxConfig() *dropbox.Config {
+	if dropboxRefreshToken == "" {
+		return dropbox.NewConfig(dropboxToken)
+	}
+
+	return dropbox.NewConfig(dropboxSession.Token(false))
+}
+
+// dropboxConfigured returns true when there is a dropbox token or a refresh token
+func dropboxConfigured() bool {
+	return dropboxRefreshToken != "" || !tokenMissing(dropboxToken)
+}
+
+// Token returns the access token renewing it when it is about to
+// expire, or always when forced after dropbox reported it expired
+func (d *dropboxAccess) Token(force bool) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !force && d.token != "" && time.Until(d.expires) > dropboxRenewMargin {
+		return d.token
+	}
+
+	token, expiresIn, err := refreshDropboxToken()
+	if err != nil {
+		fatalConfigf("Error renewing the dropbox access token: %s", err)
+	}
+
+	d.token = token
+	d.expires = time.Now().Add(expiresIn)
+
+	return d.token
+}
+
+func refreshDropboxToken() (string, time.Duration, error) {
+	if dropboxAppKey == "" {
+		return "", 0, fmt.Errorf("the dropbox app key is needed with the refresh token")
+	}
+
+	v := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {dropboxRefreshToken},
+		"client_id":     {dropboxAppKey},
+	}
+
+	// Tokens from the auth command use PKCE and need no secret
+	if dropboxAppSecret != "" {
+		v.Set("client_secret", dropboxAppSecret)
+	}
+
+	resp, err := http.PostForm(dropboxTokenURL, v)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+		Error       string `json:"error_description"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", 0, err
+	}
+
+	if out.AccessToken == "" {
+		return "", 0, fmt.Errorf("no access token returned: %s", out.Error)
+	}
+
+	return out.AccessToken, time.Duration(out.ExpiresIn) * time.Second, nil
+}
+
+// withDropbox calls with a client using the current access token, renewing
+// the token and calling again once when dropbox reports it expired
+func withDropbox(call func(c *dropbox.Client) error) error {
+	err := call(dropbox.New(dropboxConfig()))
+	if err != nil && dropboxRefreshToken != "" && strings.Contains(err.Error(), "expired_access_token") {
+		dropboxSession.Token(true)
+		err = call(dropbox.New(dropboxConfig()))
+	}
+
+	return err
+}
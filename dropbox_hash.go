@@ -0,0 +1,57 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+)
+
+// dropboxBlockSize is the block size of the dropbox content hash
+const dropboxBlockSize = 4 * 1024 * 1024
+
+// dropboxContentHash is an io.Writer computing the dropbox content_hash,
+// the sha256 of the concatenated sha256 of each 4MB block
+// https://www.dropbox.com/developers/reference/content-hash
+type dropboxContentHash struct {
+	blocks  []byte
+	current hash.Hash
+	n       int
+}
+
+func newDropboxContentHash() *dropboxContentHash {
+	return &dropboxContentHash{current: sha256.New()}
+}
+
+func (d *dropboxContentHash) Write(p []byte) (int, error) {
+	written := len(p)
+
+	for len(p) > 0 {
+		if d.n == dropboxBlockSize {
+			d.blocks = append(d.blocks, d.current.Sum(nil)...)
+			d.current.Reset()
+			d.n = 0
+		}
+
+		c := dropboxBlockSize - d.n
+		if c > len(p) {
+			c = len(p)
+		}
+
+		d.current.Write(p[:c])
+		d.n += c
+		p = p[c:]
+	}
+
+	return written, nil
+}
+
+// Hex returns the content hash as dropbox reports it
+func (d *dropboxContentHash) Hex() string {
+	b := d.blocks[:len(d.blocks):len(d.blocks)]
+	if d.n > 0 {
+		b = append(b, d.current.Sum(nil)...)
+	}
+
+	s := sha256.Sum256(b)
+	return hex.EncodeToString(s[:])
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+const (
+	linkAudiencePublic = "public"
+	linkAudienceTeam   = "team"
+	linkAudienceNoOne  = "no_one"
+)
+
+var dropboxSharingURL = "https://api.dropboxapi.com/2/sharing"
+
+// sharedLinkSettings are the dropbox settings of the attachment shared links,
+// without any the permanent public short links are created as before
+type sharedLinkSettings struct {
+	Audience        string `json:"audience,omitempty"`
+	Expires         string `json:"expires,omitempty"`
+	RequirePassword bool   `json:"require_password,omitempty"`
+	LinkPassword    string `json:"link_password,omitempty"`
+}
+
+// newSharedLinkSettings returns the settings of the options, nil when none are set.
+// The expiry is a date or how long after the migration the links expire
+func newSharedLinkSettings(audience string, expiry string, password string) *sharedLinkSettings {
+	if audience == "" && expiry == "" && password == "" {
+		return nil
+	}
+
+	s := &sharedLinkSettings{Audience: audience, LinkPassword: password, RequirePassword: password != ""}
+
+	if expiry != "" {
+		at, err := time.Parse(minCreatedAtLayout, expiry)
+		if err != nil {
+			d, derr := time.ParseDuration(expiry)
+			if derr != nil {
+				fatalConfigf("Invalid link expiry '%s' expected a date (YYYY-MM-DD) or a duration e.g. 720h", expiry)
+			}
+			at = time.Now().Add(d)
+		}
+
+		s.Expires = at.UTC().Format(time.RFC3339)
+	}
+
+	return s
+}
+
+// shareWithSettings creates the shared link of the file with the settings,
+// an existing link of the file is changed to the settings instead
+func shareWithSettings(path string, s *sharedLinkSettings) (string, error) {
+	var created struct {
+		URL string `json:"url"`
+	}
+
+	err := dropboxSharingRequest("/create_shared_link_with_settings", map[string]interface{}{"path": path, "settings": s}, &created)
+	if err == nil {
+		return created.URL, nil
+	}
+
+	var exists struct {
+		Error struct {
+			Tag      string `json:".tag"`
+			Metadata struct {
+				URL string `json:"url"`
+			} `json:"shared_link_already_exists"`
+		} `json:"error"`
+	}
+
+	e, ok := err.(*dropboxSharingError)
+	if !ok || json.Unmarshal(e.Body, &exists) != nil || exists.Error.Metadata.URL == "" {
+		return "", err
+	}
+
+	var modified struct {
+		URL string `json:"url"`
+	}
+
+	err = dropboxSharingRequest("/modify_shared_link_settings", map[string]interface{}{"url": exists.Error.Metadata.URL, "settings": s}, &modified)
+	return modified.URL, err
+}
+
+// dropboxSharingError is an error response of the dropbox sharing api
+type dropboxSharingError struct {
+	Status int
+	Body   []byte
+}
+
+func (e *dropboxSharingError) Error() string {
+	return fmt.Sprintf("dropbox sharing returned %d: %s", e.Status, e.Body)
+}
+
+// dropboxSharingRequest calls the sharing endpoints the go-dropbox package
+// has no settings for, renewing the token once when it expired
+func dropboxSharingRequest(endpoint string, body interface{}, out interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	call := func() ([]byte, error) {
+		req, err := http.NewRequest("POST", dropboxSharingURL+endpoint, bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+dropboxConfig().AccessToken)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		rb, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode >= 300 {
+			return nil, &dropboxSharingError{Status: resp.StatusCode, Body: rb}
+		}
+
+		return rb, nil
+	}
+
+	rb, err := call()
+	if e, ok := err.(*dropboxSharingError); ok && dropboxRefreshToken != "" && bytes.Contains(e.Body, []byte("expired_access_token")) {
+		dropboxSession.Token(true)
+		rb, err = call()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(rb, out)
+}
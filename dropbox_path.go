@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"sync"
+	"text/template"
+
+	trello "github.com/jnormington/go-trello"
+)
+
+// defaultDropboxPathTemplate keeps the original layout of the uploads
+const defaultDropboxPathTemplate = "/trello/{{.ListID}}/{{.CardID}}/{{.Index}}_{{.FileName}}"
+
+// maxPathSegmentLength keeps long card names from making unwieldy paths
+const maxPathSegmentLength = 100
+
+// DropboxPathData is what the dropbox path template can use, the names
+// are made safe with the filename policy
+type DropboxPathData struct {
+	BoardName string
+	ListName  string
+	CardName  string
+	BoardID   string
+	ListID    string
+	CardID    string
+	ShortLink string
+	Index     int
+	FileName  string
+}
+
+// dropboxPather builds the dropbox path of each attachment from the template,
+// as uploads overwrite, paths already used in the run get a short hash appended
+type dropboxPather struct {
+	tmpl     *template.Template
+	sanitize func(string) string
+
+	mu   sync.Mutex
+	used map[string]bool
+}
+
+// parseDropboxPathTemplate fails hard when the template is invalid
+func parseDropboxPathTemplate(text string) *template.Template {
+	t, err := template.New("dropbox-path").Option("missingkey=error").Parse(text)
+	if err != nil {
+		fatalConfigf("Invalid dropbox path template '%s': %s", text, err)
+	}
+
+	if err := t.Execute(&bytes.Buffer{}, DropboxPathData{}); err != nil {
+		fatalConfigf("Invalid dropbox path template '%s': %s", text, err)
+	}
+
+	return t
+}
+
+func newDropboxPather(text string, policy string) *dropboxPather {
+	s, ok := fileNameSanitizers[policy]
+	if !ok {
+		s = fileNameSanitizers[fileNamePolicyUnicode]
+	}
+
+	return &dropboxPather{tmpl: parseDropboxPathTemplate(text), sanitize: s, used: map[string]bool{}}
+}
+
+// Path returns the dropbox path for the attachment named name (already safe) on the card
+func (dp *dropboxPather) Path(t *TrelloOptions, card *trello.Card, i int, name string) string {
+	d := DropboxPathData{
+		BoardName: dp.segment(t.Board.Name),
+		ListName:  dp.segment(t.List.Name),
+		CardName:  dp.segment(card.Name),
+		BoardID:   t.Board.Id,
+		ListID:    card.IdList,
+		CardID:    card.Id,
+		ShortLink: card.ShortLink,
+		Index:     i,
+		FileName:  name,
+	}
+
+	var b bytes.Buffer
+	if err := dp.tmpl.Execute(&b, d); err != nil {
+		log.Fatalf("Error building the dropbox path for card: %s %s", card.Name, err)
+	}
+
+	return dp.claim(path.Clean("/"+b.String()), card.Id+name+fmt.Sprint(i))
+}
+
+// ManifestPath returns the dropbox path of the card manifest in the folder dir
+func (dp *dropboxPather) ManifestPath(card *trello.Card, dir string) string {
+	return dp.claim(path.Join(dir, manifestFileName), card.Id+dir)
+}
+
+// reset forgets the used paths so a new run can reuse them
+func (dp *dropboxPather) reset() {
+	dp.mu.Lock()
+	defer dp.mu.Unlock()
+
+	dp.used = map[string]bool{}
+}
+
+// claim marks the path p as used, appending a short hash of seed when it already is
+func (dp *dropboxPather) claim(p string, seed string) string {
+	dp.mu.Lock()
+	defer dp.mu.Unlock()
+
+	// Dropbox treats paths differing only by case as the same file
+	if dp.used[strings.ToLower(p)] {
+		ext := path.Ext(p)
+		h := sha1.Sum([]byte(seed))
+		p = fmt.Sprintf("%s_%x%s", strings.TrimSuffix(p, ext), h[:4], ext)
+	}
+
+	dp.used[strings.ToLower(p)] = true
+	return p
+}
+
+// segment makes a board, list or card name usable as a single path segment
+func (dp *dropboxPather) segment(name string) string {
+	s := strings.Trim(dp.sanitize(name), "_ ")
+	if r := []rune(s); len(r) > maxPathSegmentLength {
+		s = string(r[:maxPathSegmentLength])
+	}
+
+	s = strings.TrimRight(s, ". ")
+
+	if s == "" {
+		s = "untitled"
+	}
+
+	return s
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+
+	ch "github.com/jnormington/clubhouse-go"
+	trello "github.com/jnormington/go-trello"
+)
+
+// dryRunCard shows what the import would do for the card without changing
+// anything, when stories already exist the differences with the incoming
+// card are listed so the duplicate policy can be chosen
+func dryRunCard(c *Card, dups []ch.Story, opts *ClubhouseOptions, um *UserMap) {
+	if opts.isEpicCard(c) {
+		report.SetDryRun(c.ShortURL, c.Name, 0, "Would Create Epic", nil)
+		cardOutput.Row(c.ShortURL, "Would Create Epic", fmt.Sprintf("%d stories", len(c.Tasks)))
+		return
+	}
+
+	// No linked files are created in a dry run
+	cs := storyFromCard(c, opts, um, nil)
+	if len(dups) == 0 {
+		report.SetDryRun(c.ShortURL, c.Name, 0, "Would Create", nil)
+		cardOutput.Row(c.ShortURL, "Would Create", "")
+		return
+	}
+
+	for _, st := range dups {
+		diff := diffStory(&st, cs)
+		status := fmt.Sprintf("Duplicate (%s)", opts.OnDuplicate)
+
+		report.SetDryRun(c.ShortURL, c.Name, st.ID, status, diff)
+		cardOutput.Row(c.ShortURL, status, fmt.Sprintf("Story ID: %d", st.ID))
+
+		if len(diff) == 0 {
+			fmt.Println("\tno differences")
+		}
+		for _, d := range diff {
+			fmt.Println("\t" + d)
+		}
+	}
+}
+
+// diffStory compares the existing story with the story built for the card,
+// descriptions are compared by hash as they are usually too long to show
+func diffStory(st *ch.Story, cs *ch.CreateStory) []string {
+	var diff []string
+
+	if st.Name != cs.Name {
+		diff = append(diff, fmt.Sprintf("name: %q -> %q", st.Name, cs.Name))
+	}
+
+	if a, b := shortHash(st.Description), shortHash(cs.Description); a != b {
+		diff = append(diff, fmt.Sprintf("description: %s (%d chars) -> %s (%d chars)", a, len(st.Description), b, len(cs.Description)))
+	}
+
+	existing := map[string]bool{}
+	for _, l := range st.Labels {
+		existing[l.Name] = true
+	}
+
+	incoming := map[string]bool{}
+	for _, l := range cs.Labels {
+		incoming[l.Name] = true
+	}
+
+	var added, removed []string
+	for l := range incoming {
+		if !existing[l] {
+			added = append(added, "+"+l)
+		}
+	}
+	for l := range existing {
+		if !incoming[l] {
+			removed = append(removed, "-"+l)
+		}
+	}
+
+	if len(added)+len(removed) > 0 {
+		sort.Strings(added)
+		sort.Strings(removed)
+		diff = append(diff, "labels: "+strings.Join(append(added, removed...), " "))
+	}
+
+	return diff
+}
+
+func shortHash(s string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(s)))[:12]
+}
+
+// estimateAttachments lists what migrating the attachments of the cards would
+// upload, each uploaded file gets a dropbox shared link while url attachments
+// are only linked, so the storage and api use is known before enabling it
+func estimateAttachments(cards []trello.Card, to *TrelloOptions) {
+	attachments, err := listAttachments(to)
+	if err != nil {
+		fmt.Println("Error: Querying the attachments skipping the estimate...", err)
+		return
+	}
+
+	var files, links int
+	var total int64
+
+	fmt.Println("Attachments which would be migrated:")
+	for _, c := range cards {
+		// Saved cards were exported with their attachments already
+		if _, saved := to.SavedExport[c.Id]; saved {
+			continue
+		}
+
+		var cardFiles, cardLinks int
+		var cardBytes int64
+
+		for i := range attachments[c.Id] {
+			a := &attachments[c.Id][i]
+			if !a.IsUpload {
+				cardLinks++
+				continue
+			}
+
+			if !to.Attachments.Allows(a) {
+				continue
+			}
+
+			cardFiles++
+			cardBytes += int64(a.Bytes)
+		}
+
+		if cardFiles+cardLinks == 0 {
+			continue
+		}
+
+		fmt.Printf("\t%s %d files (%s), %d links\n", c.ShortUrl, cardFiles, megabytes(cardBytes), cardLinks)
+		files += cardFiles
+		links += cardLinks
+		total += cardBytes
+	}
+
+	fmt.Printf("Total: %d files (%s) uploaded to Dropbox with %d shared links created, %d links kept\n", files, megabytes(total), files, links)
+}
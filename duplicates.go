@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	ch "github.com/jnormington/clubhouse-go"
+)
+
+const (
+	onDuplicateSkip    = "skip"
+	onDuplicateArchive = "archive"
+	onDuplicateDelete  = "delete"
+	onDuplicateUpdate  = "update"
+
+	supersededSuffix = " -superseded"
+
+	multipleDuplicatesAsk    = "ask"
+	multipleDuplicatesNewest = "newest"
+	multipleDuplicatesOldest = "oldest"
+	multipleDuplicatesAll    = "all"
+)
+
+// findDuplicateStories returns the existing stories for the card, with a
+// story map only the mapped story otherwise the stories with the same name
+func findDuplicateStories(stories []ch.Story, opts *ClubhouseOptions, card Card) []ch.Story {
+	if opts.StoryMap != nil {
+		id, ok := opts.StoryMap[card.ShortURL]
+		if !ok {
+			return nil
+		}
+
+		for _, st := range stories {
+			if st.ID == id {
+				return []ch.Story{st}
+			}
+		}
+
+		// Mapped in the file but not listed, it may be in another project
+		return []ch.Story{{ID: id, Name: card.Name}}
+	}
+
+	var dups []ch.Story
+	for _, st := range stories {
+		if st.Name == card.Name && !st.Archived {
+			dups = append(dups, st)
+		}
+	}
+
+	return dups
+}
+
+// replaceDuplicateStories archives (renaming them as superseded) or deletes
+// the existing stories before the card is imported again
+func replaceDuplicateStories(dups []ch.Story, opts *ClubhouseOptions, card Card) {
+	for _, st := range dups {
+		var err error
+		status := "Archived Matching"
+
+		if opts.OnDuplicate == onDuplicateDelete {
+			status = "Deleted Matching"
+			err = opts.ClubhouseEntry.DeleteStory(st.ID)
+		} else {
+			_, err = opts.ClubhouseEntry.UpdateStory(ch.UpdateStory{Archived: true, Name: st.Name + supersededSuffix}, st.ID)
+		}
+
+		if err != nil {
+			fmt.Println("Error:", status, "story:", st.ID, "for card:", card.Name, "ignoring...", err)
+			continue
+		}
+
+		cardOutput.Row(card.ShortURL, status, fmt.Sprintf("Story ID: %d", st.ID))
+	}
+}
+
+// chooseDuplicateStories picks the stories -on-duplicate applies to when several
+// have the card name, story ids only grow so the newest has the highest id.
+// Returns true when the user chose to skip the card
+func chooseDuplicateStories(dups []ch.Story, opts *ClubhouseOptions, card Card) ([]ch.Story, bool) {
+	if len(dups) < 2 {
+		return dups, false
+	}
+
+	newest, oldest := dups[0], dups[0]
+	for _, st := range dups {
+		if st.ID > newest.ID {
+			newest = st
+		}
+		if st.ID < oldest.ID {
+			oldest = st
+		}
+	}
+
+	switch opts.MultipleDuplicates {
+	case multipleDuplicatesNewest:
+		return []ch.Story{newest}, false
+	case multipleDuplicatesOldest:
+		return []ch.Story{oldest}, false
+	case multipleDuplicatesAll:
+		return dups, false
+	}
+
+	// Only archiving and deleting change every story, update and skip use one
+	replacesAll := opts.OnDuplicate == onDuplicateArchive || opts.OnDuplicate == onDuplicateDelete
+
+	fmt.Printf("%d stories are named %q, which should be %s for %s?\n", len(dups), card.Name, duplicateAction(opts.OnDuplicate), card.ShortURL)
+	for i, st := range dups {
+		fmt.Printf("[%d] Story ID: %d %s\n", i, st.ID, opts.clubhouseAppURL("story", st.ID))
+	}
+
+	n := len(dups)
+	if replacesAll {
+		fmt.Printf("[%d] All of them\n", n)
+		n++
+	}
+	fmt.Printf("[%d] None, skip the card\n", n)
+
+	i := promptUserSelectResource()
+	switch {
+	case i < len(dups):
+		return []ch.Story{dups[i]}, false
+	case i == n:
+		return dups, true
+	case replacesAll && i == len(dups):
+		return dups, false
+	}
+
+	log.Fatal(errOutOfRange)
+	return nil, false
+}
+
+// duplicateAction describes what happens to the chosen story
+func duplicateAction(onDuplicate string) string {
+	switch onDuplicate {
+	case onDuplicateUpdate:
+		return "updated"
+	case onDuplicateSkip:
+		return "kept"
+	case onDuplicateDelete:
+		return "deleted"
+	}
+
+	return "archived"
+}
@@ -0,0 +1,147 @@
+package main
+
+import "regexp"
+
+var emojiShortcodeRegexp = regexp.MustCompile(`:([a-z0-9_+\-]+):`)
+
+// emojiShortcodes are the common shortcodes Trello renders
+var emojiShortcodes = map[string]string{
+	"+1":                       "👍",
+	"-1":                       "👎",
+	"100":                      "💯",
+	"alarm_clock":              "⏰",
+	"angry":                    "😠",
+	"arrow_down":               "⬇️",
+	"arrow_left":               "⬅️",
+	"arrow_right":              "➡️",
+	"arrow_up":                 "⬆️",
+	"bangbang":                 "‼️",
+	"beer":                     "🍺",
+	"bell":                     "🔔",
+	"blush":                    "😊",
+	"bomb":                     "💣",
+	"book":                     "📖",
+	"bookmark":                 "🔖",
+	"boom":                     "💥",
+	"bug":                      "🐛",
+	"bulb":                     "💡",
+	"calendar":                 "📆",
+	"camera":                   "📷",
+	"chart_with_upwards_trend": "📈",
+	"checkered_flag":           "🏁",
+	"clap":                     "👏",
+	"clipboard":                "📋",
+	"clock1":                   "🕐",
+	"cloud":                    "☁️",
+	"coffee":                   "☕",
+	"construction":             "🚧",
+	"cry":                      "😢",
+	"dart":                     "🎯",
+	"disappointed":             "😞",
+	"dizzy":                    "💫",
+	"email":                    "📧",
+	"eyes":                     "👀",
+	"fire":                     "🔥",
+	"flushed":                  "😳",
+	"gear":                     "⚙️",
+	"gift":                     "🎁",
+	"grimacing":                "😬",
+	"grin":                     "😁",
+	"grinning":                 "😀",
+	"hammer":                   "🔨",
+	"heart":                    "❤️",
+	"heart_eyes":               "😍",
+	"heavy_check_mark":         "✔️",
+	"heavy_minus_sign":         "➖",
+	"heavy_plus_sign":          "➕",
+	"hourglass":                "⌛",
+	"house":                    "🏠",
+	"hushed":                   "😯",
+	"information_source":       "ℹ️",
+	"innocent":                 "😇",
+	"joy":                      "😂",
+	"key":                      "🔑",
+	"kissing_heart":            "😘",
+	"laughing":                 "😆",
+	"link":                     "🔗",
+	"lock":                     "🔒",
+	"mag":                      "🔍",
+	"memo":                     "📝",
+	"money_with_wings":         "💸",
+	"moneybag":                 "💰",
+	"muscle":                   "💪",
+	"neutral_face":             "😐",
+	"no_entry":                 "⛔",
+	"no_entry_sign":            "🚫",
+	"ok":                       "🆗",
+	"ok_hand":                  "👌",
+	"open_mouth":               "😮",
+	"package":                  "📦",
+	"paperclip":                "📎",
+	"partying_face":            "🥳",
+	"pencil":                   "📝",
+	"pencil2":                  "✏️",
+	"point_down":               "👇",
+	"point_left":               "👈",
+	"point_right":              "👉",
+	"point_up":                 "☝️",
+	"pray":                     "🙏",
+	"pushpin":                  "📌",
+	"question":                 "❓",
+	"rage":                     "😡",
+	"raised_hands":             "🙌",
+	"recycle":                  "♻️",
+	"red_circle":               "🔴",
+	"relaxed":                  "☺️",
+	"relieved":                 "😌",
+	"rocket":                   "🚀",
+	"rotating_light":           "🚨",
+	"scream":                   "😱",
+	"see_no_evil":              "🙈",
+	"shield":                   "🛡️",
+	"shipit":                   "🐿️",
+	"skull":                    "💀",
+	"sleeping":                 "😴",
+	"slightly_smiling_face":    "🙂",
+	"smile":                    "😄",
+	"smiley":                   "😃",
+	"smirk":                    "😏",
+	"sob":                      "😭",
+	"sparkles":                 "✨",
+	"star":                     "⭐",
+	"stop_sign":                "🛑",
+	"stuck_out_tongue":         "😛",
+	"sunglasses":               "😎",
+	"sweat":                    "😓",
+	"sweat_smile":              "😅",
+	"tada":                     "🎉",
+	"thinking":                 "🤔",
+	"thinking_face":            "🤔",
+	"thumbsdown":               "👎",
+	"thumbsup":                 "👍",
+	"trophy":                   "🏆",
+	"unamused":                 "😒",
+	"unlock":                   "🔓",
+	"warning":                  "⚠️",
+	"wave":                     "👋",
+	"white_check_mark":         "✅",
+	"wink":                     "😉",
+	"worried":                  "😟",
+	"wrench":                   "🔧",
+	"x":                        "❌",
+	"yellow_circle":            "🟡",
+	"green_circle":             "🟢",
+	"zap":                      "⚡",
+}
+
+// convertEmojiShortcodes replaces the known :shortcode: with its
+// unicode emoji, unknown shortcodes are left as they are
+func convertEmojiShortcodes(s string) string {
+	return emojiShortcodeRegexp.ReplaceAllStringFunc(s, func(m string) string {
+		if e, ok := emojiShortcodes[m[1:len(m)-1]]; ok {
+			return e
+		}
+
+		return m
+	})
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"strings"
+)
+
+// envPrefix starts the environment variable of each setting,
+// -dropbox-path-template is TRELLO_TO_CLUBHOUSE_DROPBOX_PATH_TEMPLATE
+const envPrefix = "TRELLO_TO_CLUBHOUSE_"
+
+// applyEnvTokens uses the tokens from the environment,
+// they come before the config file and the stored tokens
+func applyEnvTokens() {
+	useStoredToken(&trelloKey, os.Getenv("TRELLO_KEY"))
+	useStoredToken(&trelloToken, os.Getenv("TRELLO_TOKEN"))
+	useStoredToken(&clubHouseToken, os.Getenv("CLUBHOUSE_TOKEN"))
+	useStoredToken(&dropboxToken, os.Getenv("DROPBOX_TOKEN"))
+	useStoredToken(&dropboxRefreshToken, os.Getenv("DROPBOX_REFRESH_TOKEN"))
+	useStoredToken(&dropboxAppKey, os.Getenv("DROPBOX_APP_KEY"))
+	useStoredToken(&dropboxAppSecret, os.Getenv("DROPBOX_APP_SECRET"))
+}
+
+// applyEnvSettings sets the flags which weren't given on the command line from
+// the environment, they are set on the flag set so the config file skips them
+func applyEnvSettings(fs *flag.FlagSet) {
+	given := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	fs.VisitAll(func(f *flag.Flag) {
+		v, ok := os.LookupEnv(envName(f.Name))
+		if !ok || given[f.Name] {
+			return
+		}
+
+		// Lists are additive for flags so start from empty
+		if l, ok := f.Value.(*stringList); ok {
+			*l = nil
+		}
+
+		if err := fs.Set(f.Name, v); err != nil {
+			fatalConfigf("Invalid value for %s: %s", envName(f.Name), err)
+		}
+	})
+}
+
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.Replace(flagName, "-", "_", -1))
+}
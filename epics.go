@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	ch "github.com/jnormington/clubhouse-go"
+)
+
+const epicCardsAll = "all"
+
+// createEpic is the body for creating a clubhouse epic
+type createEpic struct {
+	Name          string           `json:"name"`
+	Description   string           `json:"description"`
+	CreatedAt     *time.Time       `json:"created_at,omitempty"`
+	Deadline      *time.Time       `json:"deadline,omitempty"`
+	RequestedByID string           `json:"requested_by_id,omitempty"`
+	OwnerIds      []string         `json:"owner_ids"`
+	Labels        []ch.CreateLabel `json:"labels"`
+	ExternalID    string           `json:"external_id,omitempty"`
+}
+
+// createEpicStory is the body for creating a story in an epic
+type createEpicStory struct {
+	Name            string     `json:"name"`
+	ProjectID       int64      `json:"project_id"`
+	EpicID          int64      `json:"epic_id"`
+	WorkflowStateID int64      `json:"workflow_state_id"`
+	StoryType       string     `json:"story_type"`
+	RequestedByID   string     `json:"requested_by_id,omitempty"`
+	OwnerIds        []string   `json:"owner_ids,omitempty"`
+	Deadline        *time.Time `json:"deadline,omitempty"`
+	CreatedAt       *time.Time `json:"created_at,omitempty"`
+}
+
+type clubhouseEntity struct {
+	ID     int64  `json:"id"`
+	AppURL string `json:"app_url"`
+}
+
+// isEpicCard returns true when the card is converted into an epic,
+// either all cards are or the cards with the epic label
+func (co *ClubhouseOptions) isEpicCard(card *Card) bool {
+	if co.EpicCards == "" {
+		return false
+	}
+
+	if co.EpicCards == epicCardsAll {
+		return true
+	}
+
+	for _, l := range card.Labels {
+		if l == co.EpicCards {
+			return true
+		}
+	}
+
+	return false
+}
+
+// importCardAsEpic creates an epic for the card and a story in
+// the epic for each of its checklist items
+func importCardAsEpic(card *Card, opts *ClubhouseOptions, um *UserMap) (int64, error) {
+	requestedBy := requestedByFromTrelloCard(card, opts, um)
+	desc, overflow := buildDescriptionWithOverflow(card, opts)
+	if len(overflow) > 0 {
+		report.AddDataLoss(lossDescriptions, card.ShortURL, card.Name, "epics have no comments to continue the description in")
+	}
+
+	e := createEpic{
+		Name:          card.Name,
+		Description:   desc,
+		CreatedAt:     opts.storyTime(card.CreatedAt),
+		Deadline:      opts.deadline(card.DueDate),
+		RequestedByID: requestedBy,
+		OwnerIds:      mapOwnersFromTrelloCard(card, opts, um),
+		Labels:        *buildLabels(card, opts),
+	}
+
+	var epic clubhouseEntity
+	if err := clubhouseRequest("POST", "/epics", e, &epic); err != nil {
+		return 0, err
+	}
+
+	for _, t := range card.Tasks {
+		s := createEpicStory{
+			Name:            t.Description,
+			ProjectID:       opts.Project.ID,
+			EpicID:          epic.ID,
+			WorkflowStateID: opts.State.ID,
+			StoryType:       opts.storyTypeFor(card),
+			RequestedByID:   requestedBy,
+			OwnerIds:        mapTaskOwner(t, um),
+			Deadline:        opts.deadline(t.DueDate),
+			CreatedAt:       opts.storyTime(card.CreatedAt),
+		}
+
+		if t.Completed && opts.DoneState != nil {
+			s.WorkflowStateID = opts.DoneState.ID
+		}
+
+		if err := clubhouseRequest("POST", "/stories", s, nil); err != nil {
+			fmt.Println("Error: Creating story for checklist item:", t.Description, "on card:", card.Name, "ignoring...", err)
+		}
+	}
+
+	return epic.ID, nil
+}
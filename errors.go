@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The causes the failed cards are grouped by, in the order they are summarized
+const (
+	causeAuth       = "auth"
+	causeRateLimit  = "rate_limit"
+	causeValidation = "validation"
+	causeNotFound   = "not_found"
+	causeOther      = "other"
+)
+
+var failureCauses = []string{causeAuth, causeRateLimit, causeValidation, causeNotFound, causeOther}
+
+// causeRemediation is what to do about the failures of each cause
+var causeRemediation = map[string]string{
+	causeAuth:       "Check the tokens are current and their member can write to the project, then run again with -on-duplicate=skip",
+	causeRateLimit:  "Wait for the rate limit or free up the quota, then run again with -on-duplicate=skip",
+	causeValidation: "The story was rejected as invalid, check the cards for overlong fields or a bad mapping",
+	causeNotFound:   "Something the story refers to is gone, check the project, workflow state and members still exist",
+	causeOther:      "Check the errors above, the network or the service status",
+}
+
+// AuthError is a request refused as the token is invalid or lacks permission
+type AuthError struct{ Err error }
+
+// RateLimitError is a request refused by a rate limit or quota
+type RateLimitError struct{ Err error }
+
+// ValidationError is a request rejected as invalid
+type ValidationError struct{ Err error }
+
+// NotFoundError is a request for something which doesn't exist
+type NotFoundError struct{ Err error }
+
+func (e *AuthError) Error() string       { return "not authorized: " + e.Err.Error() }
+func (e *RateLimitError) Error() string  { return "rate limited: " + e.Err.Error() }
+func (e *ValidationError) Error() string { return "rejected as invalid: " + e.Err.Error() }
+func (e *NotFoundError) Error() string   { return "not found: " + e.Err.Error() }
+
+func (e *AuthError) Unwrap() error       { return e.Err }
+func (e *RateLimitError) Unwrap() error  { return e.Err }
+func (e *ValidationError) Unwrap() error { return e.Err }
+func (e *NotFoundError) Unwrap() error   { return e.Err }
+
+// statusInError finds the status code in the errors of the api packages
+var statusInError = regexp.MustCompile(`(?:returned|status|code) (\d{3})\b`)
+
+// classifyError wraps the error in the type of its cause, errors from the
+// api packages only have a message so the status code is taken from it
+func classifyError(err error) error {
+	if err == nil || errorCause(err) != causeOther {
+		return err
+	}
+
+	status := 0
+	var api *clubhouseAPIError
+	if errors.As(err, &api) {
+		status = api.Status
+	} else if m := statusInError.FindStringSubmatch(err.Error()); m != nil {
+		status, _ = strconv.Atoi(m[1])
+	}
+
+	msg := strings.ToLower(err.Error())
+
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden,
+		strings.Contains(msg, "unauthorized"), strings.Contains(msg, "invalid_access_token"):
+		return &AuthError{err}
+	case status == http.StatusTooManyRequests, isHardLimitError(err):
+		return &RateLimitError{err}
+	case status == http.StatusBadRequest || status == http.StatusUnprocessableEntity:
+		return &ValidationError{err}
+	case status == http.StatusNotFound:
+		return &NotFoundError{err}
+	}
+
+	return err
+}
+
+// errorCause returns the cause of a classified error
+func errorCause(err error) string {
+	var (
+		auth       *AuthError
+		rateLimit  *RateLimitError
+		validation *ValidationError
+		notFound   *NotFoundError
+	)
+
+	switch {
+	case errors.As(err, &auth):
+		return causeAuth
+	case errors.As(err, &rateLimit):
+		return causeRateLimit
+	case errors.As(err, &validation):
+		return causeValidation
+	case errors.As(err, &notFound):
+		return causeNotFound
+	}
+
+	return causeOther
+}
+
+// printFailureCauses lists how many cards failed of each cause with
+// what to do about it, for callers holding the report lock
+func (r *Report) printFailureCauses() {
+	if len(r.FailureCauses) == 0 {
+		return
+	}
+
+	fmt.Println("\nFailed cards by cause")
+	for _, c := range failureCauses {
+		if n := r.FailureCauses[c]; n > 0 {
+			fmt.Printf("\t- %s (%d): %s\n", c, n, causeRemediation[c])
+		}
+	}
+}
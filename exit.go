@@ -0,0 +1,32 @@
+package main
+
+import (
+	"log"
+	"os"
+)
+
+// The exit codes let wrapper scripts tell the outcome of a run apart,
+// any other fatal error exits with exitFailures like log.Fatal
+const (
+	exitSuccess     = 0
+	exitFailures    = 1
+	exitConfigError = 2
+	exitAborted     = 3
+)
+
+// fatalConfig stops on an invalid option or missing access (tokens, board, project...)
+func fatalConfig(v ...interface{}) {
+	log.Print(v...)
+	os.Exit(exitConfigError)
+}
+
+func fatalConfigf(format string, v ...interface{}) {
+	log.Printf(format, v...)
+	os.Exit(exitConfigError)
+}
+
+// abortRun stops when the user chooses not to continue
+func abortRun(v ...interface{}) {
+	log.Print(v...)
+	os.Exit(exitAborted)
+}
@@ -2,27 +2,54 @@ package main
 
 import (
 	"fmt"
-	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"os"
 	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/jnormington/go-trello"
 	"github.com/tj/go-dropbox"
 	"github.com/variadico/lctime"
+	"golang.org/x/text/unicode/norm"
 )
 
-var dateLayout = "2006-01-02T15:04:05.000Z"
+// dateLayouts are tried in order when parsing the trello dates,
+// layouts given with -date-layouts are tried first
+var dateLayouts = []string{
+	"2006-01-02T15:04:05.000Z",
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
 var safeFileNameRegexp = regexp.MustCompile(`[^a-zA-Z0-9_.]+`)
 var localeId = "America/Boise"
+var lctimeMu sync.Mutex
+
+// Uploads are retried when they don't match the download
+const maxUploadAttempts = 3
+
+const (
+	colorLabelsColor = "color"
+	colorLabelsSkip  = "skip"
+)
 
 // Card holds all the attributes needed for migrating a complete card from Trello to Clubhouse
 type Card struct {
+	ID          string            `json:"id"`
 	Name        string            `json:"name"`
 	Desc        string            `json:"desc"`
 	Labels      []string          `json:"labels"`
 	DueDate     *time.Time        `json:"due_date"`
+	DueComplete bool              `json:"due_complete"`
+	CoverColor  string            `json:"cover_color"`
+	IsTemplate  bool              `json:"is_template"`
 	IDCreator   string            `json:"id_creator"`
 	IDOwners    []string          `json:"id_owners"`
 	CreatedAt   *time.Time        `json:"created_at"`
@@ -30,13 +57,33 @@ type Card struct {
 	Tasks       []Task            `json:"checklists"`
 	Position    float32           `json:"position"`
 	ShortURL    string            `json:"url"`
+	IDList      string            `json:"id_list"`
 	Attachments map[string]string `json:"attachments"`
+	Links       map[string]string `json:"links"`
+	Stickers    []string          `json:"stickers"`
+	Badges      CardBadges        `json:"badges"`
+
+	TimeInLists map[string]time.Duration `json:"time_in_lists"`
+	// PositionRank is how far down its list the card is from 0 to 1
+	PositionRank float64 `json:"position_rank"`
+}
+
+// CardBadges is the at a glance information trello shows on a card
+type CardBadges struct {
+	Attachments       int  `json:"attachments"`
+	CheckItems        int  `json:"check_items"`
+	CheckItemsChecked int  `json:"check_items_checked"`
+	Comments          int  `json:"comments"`
+	Votes             int  `json:"votes"`
+	Description       bool `json:"description"`
 }
 
 // Task builds a basic object based off trello.Task
 type Task struct {
-	Completed   bool   `json:"completed"`
-	Description string `json:"description"`
+	Completed   bool       `json:"completed"`
+	Description string     `json:"description"`
+	IDOwner     string     `json:"id_owner"`
+	DueDate     *time.Time `json:"due_date"`
 }
 
 // Comment builds a basic object based off trello.Comment
@@ -47,68 +94,180 @@ type Comment struct {
 	CreatedAt   *time.Time
 }
 
-// ProcessCardsForExporting takes *[]trello.Card, *TrelloOptions and builds up a Card
-// which consists of calling other functions to make the api calls to Trello
-// for the relevant attributes of a card returns *[]Card
-func ProcessCardsForExporting(crds *[]trello.Card, opts *TrelloOptions) *[]Card {
-	var cards []Card
+// ExportCards streams the cards to the import as they are built from the trello
+// api calls, in the order of the list. At most opts.Concurrency cards are exported
+// ahead of the import so memory stays flat and stories appear straight away
+func ExportCards(crds *[]trello.Card, opts *TrelloOptions) <-chan Card {
+	pending := make(chan chan Card, opts.Concurrency)
+	cards := make(chan Card)
+	ranks := positionRanks(crds)
+
+	go func() {
+		defer close(pending)
+
+		for i := range *crds {
+			if stopRequested() {
+				return
+			}
+
+			c := make(chan Card, 1)
+
+			// Blocks while the import is behind giving the backpressure
+			pending <- c
+			go func(card *trello.Card) {
+				cd, saved := opts.SavedExport[card.Id]
+				if !saved {
+					cd = processCardForExporting(card, opts)
+				}
+				cd.PositionRank = ranks[card.Id]
+				cardOutput.Event("card_exported", cd.ShortURL, cd.Name)
+				c <- cd
+			}(&(*crds)[i])
+		}
+	}()
+
+	go func() {
+		defer close(cards)
+
+		for c := range pending {
+			cards <- <-c
+		}
+	}()
+
+	return cards
+}
+
+func processCardForExporting(card *trello.Card, opts *TrelloOptions) Card {
+	var c Card
+
+	details := getCardDetails(card)
+	actions := getCardActions(card)
+
+	c.ID = card.Id
+	c.Name = opts.transformText(card.Name)
+	c.Desc = opts.transformBody(card.Desc)
+	c.Labels = getLabelsFlattenFromCard(card, opts)
+	c.DueDate = parseCardDate(card, "due", card.Due)
+	c.DueComplete = details.DueComplete
+	c.CoverColor = details.Cover.Color
+	c.IsTemplate = details.IsTemplate
+	c.IDCreator, c.CreatedAt, c.Comments = getCommentsAndCardCreator(card, actions, opts.CommentFilter)
+	if c.CreatedAt == nil {
+		c.CreatedAt = createdAtFromID(card.Id)
+		report.AddDataLoss(lossMissingActions, card.ShortUrl, card.Name, "no create action in the card history")
+	}
+	if c.IDCreator == "" {
+		c.IDCreator = opts.fallbackCreator(actions)
+	}
+	c.TimeInLists = getTimeInLists(card, actions)
+	c.Tasks = getCheckListsForCard(card)
+	if opts.DescChecklists == descriptionChecklistsTasks {
+		var tasks []Task
+		c.Desc, tasks = extractDescriptionChecklist(c.Desc)
+		c.Tasks = append(c.Tasks, tasks...)
+	}
+	c.Position = card.Pos
+	c.ShortURL = card.ShortUrl
+	c.IDList = card.IdList
+	c.IDOwners = card.IdMembers
+	c.Stickers = getStickersForCard(card)
+	c.Badges = CardBadges{
+		Attachments:       card.Badges.Attachments,
+		CheckItems:        card.Badges.CheckItems,
+		CheckItemsChecked: card.Badges.CheckItemsChecked,
+		Comments:          card.Badges.Comments,
+		Votes:             card.Badges.Votes,
+		Description:       card.Badges.Description,
+	}
+
+	c.Desc = opts.CardTitles.expandCardLinks(c.Desc)
+	for i := range c.Comments {
+		c.Comments[i].Text = opts.CardTitles.expandCardLinks(opts.transformBody(c.Comments[i].Text))
+	}
+
+	report.SetTimeInLists(c.ShortURL, c.Name, c.TimeInLists)
+
+	if opts.Attachments.Enabled {
+		var uploaded map[string]string
+		c.Attachments, c.Links, uploaded = downloadCardAttachmentsUploadToDropbox(card, opts)
+		rewriteCommentAttachments(c.Comments, uploaded)
+	}
+
+	return c
+}
+
+// Trello returns the newest actions first and at most this many per request
+const actionsPageSize = 1000
+
+var cardActionsFilter = "createCard,copyCard,convertToCardFromCheckItem,moveCardToBoard,commentCard,updateCard:idList"
 
-	for _, card := range *crds {
-		var c Card
+// getCardActions pages through the whole history of the card, card.Actions
+// only returns the latest actions losing the creator and early comments
+func getCardActions(card *trello.Card) []trello.Action {
+	var actions []trello.Action
 
-		c.Name = card.Name
-		c.Desc = card.Desc
-		c.Labels = getLabelsFlattenFromCard(&card)
-		c.DueDate = parseDateOrReturnNil(card.Due)
-		c.IDCreator, c.CreatedAt, c.Comments = getCommentsAndCardCreator(&card)
-		c.Tasks = getCheckListsForCard(&card)
-		c.Position = card.Pos
-		c.ShortURL = card.ShortUrl
-		c.IDOwners = card.IdMembers
+	params := url.Values{"filter": {cardActionsFilter}, "limit": {strconv.Itoa(actionsPageSize)}}
+	for {
+		var page []trello.Action
+		if err := trelloRequest("GET", "/cards/"+card.Id+"/actions", params, &page); err != nil {
+			fmt.Println("Error: Querying the actions for:", card.Name, "ignoring...", err)
+			break
+		}
 
-		if opts.ProcessImages {
-			c.Attachments = downloadCardAttachmentsUploadToDropbox(&card)
+		actions = append(actions, page...)
+		if len(page) < actionsPageSize {
+			break
 		}
 
-		cards = append(cards, c)
+		params.Set("before", page[len(page)-1].Id)
 	}
 
-	return &cards
+	return actions
 }
 
-func getCommentsAndCardCreator(card *trello.Card) (string, *time.Time, []Comment) {
+func getCommentsAndCardCreator(card *trello.Card, actions []trello.Action, filter commentFilter) (string, *time.Time, []Comment) {
 	var creator string
 	var createdAt *time.Time
 	var comments []Comment
 
-	actions, err := card.Actions()
-	if err != nil {
-		fmt.Println("Error: Querying the actions for:", card.Name, "ignoring...", err)
-	}
-
 	for _, a := range actions {
 		if a.Type == "commentCard" && a.Data.Text != "" {
 			c := Comment{
 				Text:        a.Data.Text,
 				IDCreator:   a.MemberCreator.Id,
 				CreatorName: a.MemberCreator.FullName,
-				CreatedAt:   parseDateOrReturnNil(a.Date),
+				CreatedAt:   parseCardDate(card, "comment", a.Date),
+			}
+			if filter.Keeps(&a, c.CreatedAt) {
+				comments = append(comments, c)
 			}
-			comments = append(comments, c)
 
-		} else if a.Type == "createCard" {
+		} else if a.Type == "createCard" || a.Type == "copyCard" || a.Type == "convertToCardFromCheckItem" {
 			creator = a.MemberCreator.Id
-			createdAt = parseDateOrReturnNil(a.Date)
+			createdAt = parseCardDate(card, "created", a.Date)
 		}
 	}
 
 	return creator, createdAt, comments
 }
 
+// getCheckListsForCard queries the checklists directly as the member
+// and due date of advanced checklist items aren't in the trello client
 func getCheckListsForCard(card *trello.Card) []Task {
 	var tasks []Task
 
-	checklists, err := card.Checklists()
+	var checklists []struct {
+		Name       string `json:"name"`
+		CheckItems []struct {
+			Name     string `json:"name"`
+			State    string `json:"state"`
+			IDMember string `json:"idMember"`
+			Due      string `json:"due"`
+		} `json:"checkItems"`
+	}
+
+	params := url.Values{"fields": {"name"}, "checkItem_fields": {"name,state,idMember,due"}}
+	err := trelloRequest("GET", "/cards/"+card.Id+"/checklists", params, &checklists)
 	if err != nil {
 		fmt.Println("Error: Occurred querying checklists for:", card.Name, "ignoring...", err)
 	}
@@ -123,6 +282,8 @@ func getCheckListsForCard(card *trello.Card) []Task {
 			t := Task{
 				Completed:   completed,
 				Description: fmt.Sprintf("%s - %s", cl.Name, i.Name),
+				IDOwner:     i.IDMember,
+				DueDate:     parseCardDate(card, "checklist item due", i.Due),
 			}
 
 			tasks = append(tasks, t)
@@ -132,89 +293,293 @@ func getCheckListsForCard(card *trello.Card) []Task {
 	return tasks
 }
 
-func getLabelsFlattenFromCard(card *trello.Card) []string {
+func getStickersForCard(card *trello.Card) []string {
+	var stickers []struct {
+		Image string `json:"image"`
+	}
+
+	err := trelloRequest("GET", "/cards/"+card.Id+"/stickers", nil, &stickers)
+	if err != nil {
+		fmt.Println("Error: Querying stickers for:", card.Name, "ignoring...", err)
+	}
+
+	var names []string
+	for _, s := range stickers {
+		names = append(names, s.Image)
+	}
+
+	return names
+}
+
+func getLabelsFlattenFromCard(card *trello.Card, opts *TrelloOptions) []string {
 	var labels []string
 
 	for _, l := range card.Labels {
-		labels = append(labels, l.Name)
+		if n := labelName(l.Name, l.Color, opts.ColorLabels); n != "" {
+			labels = append(labels, n)
+		}
 	}
 
 	return labels
 }
 
-func parseDateOrReturnNil(strDate string) *time.Time {
-	d, err := time.Parse(dateLayout, strDate)
+// labelName names a trello label with a color but no name after its color
+// rather than creating a blank clubhouse label, empty when it is skipped
+func labelName(name string, color string, policy string) string {
+	if name = norm.NFC.String(strings.TrimSpace(name)); name != "" {
+		return name
+	}
+
+	if color == "" || policy == colorLabelsSkip {
+		return ""
+	}
+
+	return "trello-" + color
+}
+
+// createdAtFromID gets the creation time trello encodes in the first
+// 8 hex characters of the id, used when the card has no create action
+func createdAtFromID(id string) *time.Time {
+	if len(id) < 8 {
+		return nil
+	}
+
+	secs, err := strconv.ParseInt(id[:8], 16, 64)
 	if err != nil {
-		//If the date isn't parseable from trello api just return nil
 		return nil
 	}
 
-	return &d
+	t := time.Unix(secs, 0).UTC()
+	return &t
+}
+
+func parseDateOrReturnNil(strDate string) *time.Time {
+	for _, l := range dateLayouts {
+		if d, err := time.Parse(l, strDate); err == nil {
+			return &d
+		}
+	}
+
+	//If the date isn't parseable from trello api just return nil
+	return nil
 }
 
-func downloadCardAttachmentsUploadToDropbox(card *trello.Card) map[string]string {
+// parseCardDate parses a date of the card, a date which can't be parsed
+// is logged and added to the report rather than silently dropped
+func parseCardDate(card *trello.Card, field string, strDate string) *time.Time {
+	if strDate == "" {
+		return nil
+	}
+
+	d := parseDateOrReturnNil(strDate)
+	if d == nil {
+		fmt.Println("Error: Parsing the", field, "date:", strDate, "for:", card.Name, "ignoring...")
+		report.AddDateError(card.ShortUrl, card.Name, DateErrorReport{Field: field, Value: strDate})
+	}
+
+	return d
+}
+
+// downloadCardAttachmentsUploadToDropbox uploads the file attachments to dropbox
+// returning their shared links, attachments which are only a url (Google Docs, Figma...)
+// aren't downloaded and are returned in the second map with their original url.
+// The third map has the shared links keyed by the trello attachment id and url.
+// The uploads run concurrently bounded by the upload slots shared across all cards
+func downloadCardAttachmentsUploadToDropbox(card *trello.Card, opts *TrelloOptions) (map[string]string, map[string]string, map[string]string) {
 	sharedLinks := map[string]string{}
-	config := dropbox.NewConfig(dropboxToken)
-	c := dropbox.New(config)
+	urlLinks := map[string]string{}
+	uploaded := map[string]string{}
 
 	attachments, err := card.Attachments()
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	namer := newFileNamer(opts.FileNamePolicy)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var manifests cardManifests
+
 	for i, f := range attachments {
-		name := safeFileNameRegexp.ReplaceAllString(f.Name, "_")
-		path := fmt.Sprintf("/trello/%s/%s/%d%s%s", card.IdList, card.Id, i, "_", name)
+		// Links are always kept, the filter and the max size are for the uploads
+		if !f.IsUpload {
+			urlLinks[f.Name] = f.Url
+			continue
+		}
 
-		r := downloadTrelloAttachment(&f)
+		if !opts.Attachments.Filter.Allows(&f) {
+			fmt.Println("Skipping attachment:", f.Name, "on card:", card.Name, "due to its type", attachmentMimeType(&f))
+			continue
+		}
 
-		lctime.SetLocale(localeId)
-		n := lctime.Strftime("%Y-%m-%dT%H:%M:%SZ", time.Now())
+		if opts.Attachments.TooLarge(&f) {
+			fmt.Println("Skipping attachment:", f.Name, "on card:", card.Name, "larger than", megabytes(opts.Attachments.MaxSize))
+			report.AddAttachment(card.ShortUrl, card.Name, AttachmentReport{Name: f.Name, Size: int64(f.Bytes), Error: "larger than the max attachment size"})
+			continue
+		}
 
-		if err != nil {
-			log.Fatalf("Error occurred downloading file from trello... %s\n", err)
-		} else {
-			u := dropbox.UploadInput{Path: path, Mode: "overwrite", AutoRename: false, Mute: true,
-				ClientModified: n, Reader: r}
-
-			o, err := c.Files.Upload(&u)
-
-			r.Close()
-			if err != nil {
-				log.Fatalf("Error occurred uploading file: '%s' to dropbox continuing. Error: '%s'\n", o.PathDisplay, err)
-			} else {
-				sh := dropbox.NewSharing(config)
-
-				listInput := dropbox.ListShareLinksInput{Path: o.PathDisplay}
-				links, _ := sh.ListSharedLinks(&listInput)
-
-				if len(links.Links) == 0 {
-					sl := dropbox.CreateSharedLinkInput{ Path: o.PathDisplay, ShortURL: true }
-
-					link, err := sh.CreateSharedLink(&sl)
-
-					// Must be success created a shared url
-					if err != nil {
-						log.Printf("Error occurred sharing file: '%s' to dropbox continuing. Error: '%s'\n", o.PathDisplay, err)
-					} else {
-						sharedLinks[name] = link.URL
-					}
-				} else {
-					sharedLinks[name] = links.Links[0].URL
+		name := namer.Name(f.Name, f.Id)
+		path := opts.DropboxPaths.Path(opts, card, i, name)
+		if opts.AttachmentKey != "" {
+			path += encryptedSuffix
+		}
+
+		wg.Add(1)
+		go func(f trello.Attachment) {
+			defer wg.Done()
+
+			opts.uploadSlots <- struct{}{}
+			defer func() { <-opts.uploadSlots }()
+
+			if link, ok := uploadAttachmentToDropbox(card, &f, path, opts); ok {
+				mu.Lock()
+				sharedLinks[name] = link
+				uploaded[f.Id] = link
+				uploaded[f.Url] = link
+				mu.Unlock()
+
+				if opts.Manifests {
+					manifests.Add(opts, &f, path, link)
 				}
 			}
+		}(f)
+	}
+
+	wg.Wait()
+
+	if opts.Manifests && len(manifests.entries) > 0 {
+		manifests.Upload(opts, card)
+	}
+
+	return sharedLinks, urlLinks, uploaded
+}
+
+// uploadAttachmentToDropbox uploads the attachment, encrypted when there is a key,
+// and shares it with the link settings when there are some
+func uploadAttachmentToDropbox(card *trello.Card, f *trello.Attachment, path string, opts *TrelloOptions) (string, bool) {
+	staged, err := stageTrelloAttachment(f)
+	if _, ok := err.(*invalidDownloadError); ok {
+		fmt.Println("Warning: Skipping attachment:", f.Name, "on card:", card.Name, "invalid download:", err)
+		report.AddAttachment(card.ShortUrl, card.Name, AttachmentReport{Name: f.Name, Path: path, Error: fmt.Sprintf("invalid download: %s", err)})
+		return "", false
+	} else if err != nil {
+		log.Fatalf("Error occurred downloading file from trello... %s\n", err)
+	}
+	defer staged.Remove()
+
+	ar := AttachmentReport{Name: f.Name, Path: path, Size: staged.Size, SHA256: staged.SHA256}
+
+	if opts.AttachmentKey != "" {
+		if err := staged.Encrypt(opts.AttachmentKey); err != nil {
+			log.Fatalf("Error occurred encrypting file: '%s' Error: '%s'\n", f.Name, err)
+		}
+	}
+
+	var o *dropbox.UploadOutput
+	for !ar.Verified && ar.Attempts < maxUploadAttempts {
+		ar.Attempts++
+
+		err := retryOnHardLimit(path, func() error {
+			return withDropbox(func(c *dropbox.Client) (err error) {
+				o, err = uploadStagedAttachment(c, staged, path)
+				return err
+			})
+		})
+
+		if isHardLimitError(err) {
+			ar.Error = fmt.Sprintf("upload skipped: %s", err)
+			report.AddAttachment(card.ShortUrl, card.Name, ar)
+			return "", false
+		} else if err != nil {
+			log.Fatalf("Error occurred uploading file: '%s' to dropbox continuing. Error: '%s'\n", path, err)
+		}
+
+		if ar.Verified = staged.Matches(o); !ar.Verified {
+			fmt.Println("Warning: Uploaded file:", path, "doesn't match the downloaded attachment, uploading again...")
 		}
 	}
 
-	return sharedLinks
+	if !ar.Verified {
+		ar.Error = "uploaded file doesn't match the downloaded attachment"
+	}
+	report.AddAttachment(card.ShortUrl, card.Name, ar)
+
+	if opts.LinkSettings != nil {
+		link, err := shareWithSettings(o.PathDisplay, opts.LinkSettings)
+		if err != nil {
+			log.Printf("Error occurred sharing file: '%s' to dropbox continuing. Error: '%s'\n", o.PathDisplay, err)
+			return "", false
+		}
+
+		return link, true
+	}
+
+	sh := dropbox.NewSharing(dropboxConfig())
+
+	listInput := dropbox.ListShareLinksInput{Path: o.PathDisplay}
+	links, _ := sh.ListSharedLinks(&listInput)
+
+	if links != nil && len(links.Links) > 0 {
+		return links.Links[0].URL, true
+	}
+
+	sl := dropbox.CreateSharedLinkInput{Path: o.PathDisplay, ShortURL: true}
+
+	link, err := sh.CreateSharedLink(&sl)
+
+	// Must be success created a shared url
+	if err != nil {
+		log.Printf("Error occurred sharing file: '%s' to dropbox continuing. Error: '%s'\n", o.PathDisplay, err)
+		return "", false
+	}
+
+	return link.URL, true
 }
 
-func downloadTrelloAttachment(attachment *trello.Attachment) io.ReadCloser {
-	resp, err := http.Get(attachment.Url)
+func uploadStagedAttachment(c *dropbox.Client, staged *stagedAttachment, path string) (*dropbox.UploadOutput, error) {
+	r, err := os.Open(staged.Path)
+	if err != nil {
+		log.Fatalf("Error occurred reading downloaded file: '%s' Error: '%s'\n", staged.Path, err)
+	}
+	defer r.Close()
+
+	u := dropbox.UploadInput{Path: path, Mode: "overwrite", AutoRename: false, Mute: true,
+		ClientModified: clientModifiedNow(), Reader: r}
+
+	return c.Files.Upload(&u)
+}
+
+// lctime uses a global locale so the calls are serialized
+func clientModifiedNow() string {
+	lctimeMu.Lock()
+	defer lctimeMu.Unlock()
+
+	lctime.SetLocale(localeId)
+	return lctime.Strftime("%Y-%m-%dT%H:%M:%SZ", time.Now())
+}
 
+// downloadTrelloAttachment authenticates the downloads of files hosted on trello,
+// which otherwise return 401, the credentials aren't sent to any other host
+func downloadTrelloAttachment(attachment *trello.Attachment) *http.Response {
+	req, err := http.NewRequest("GET", attachment.Url, nil)
 	if err != nil {
 		log.Fatalf("Error in download Trello attachment %s\n", err)
 	}
 
-	return resp.Body
+	if isTrelloHost(req.URL.Hostname()) {
+		req.Header.Set("Authorization", fmt.Sprintf(`OAuth oauth_consumer_key="%s", oauth_token="%s"`, trelloKey, trelloToken))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Fatalf("Error in download Trello attachment %s\n", err)
+	}
+
+	return resp
+}
+
+func isTrelloHost(host string) bool {
+	host = strings.ToLower(host)
+	return host == "trello.com" || strings.HasSuffix(host, ".trello.com")
 }
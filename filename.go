@@ -0,0 +1,61 @@
+package main
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	fileNamePolicyUnicode = "unicode"
+	fileNamePolicyASCII   = "ascii"
+	fileNamePolicyNone    = "none"
+)
+
+var unicodeFileNameRegexp = regexp.MustCompile(`[^\p{L}\p{N}\p{M}_.-]+`)
+var unsafeFileNameRegexp = regexp.MustCompile(`[/\\:*?"<>|\x00-\x1f]+`)
+
+var fileNameSanitizers = map[string]func(string) string{
+	fileNamePolicyUnicode: func(n string) string { return unicodeFileNameRegexp.ReplaceAllString(n, "_") },
+	fileNamePolicyASCII:   func(n string) string { return safeFileNameRegexp.ReplaceAllString(n, "_") },
+	// Only strips what dropbox and the common file systems won't accept
+	fileNamePolicyNone: func(n string) string { return unsafeFileNameRegexp.ReplaceAllString(n, "_") },
+}
+
+// fileNamer makes attachment names safe using the selected policy
+// and unique within a card by appending a short hash on duplicates
+type fileNamer struct {
+	sanitize func(string) string
+	used     map[string]bool
+}
+
+func newFileNamer(policy string) *fileNamer {
+	s, ok := fileNameSanitizers[policy]
+	if !ok {
+		s = fileNameSanitizers[fileNamePolicyUnicode]
+	}
+
+	return &fileNamer{sanitize: s, used: map[string]bool{}}
+}
+
+// Name returns the safe name for the attachment, the id is used
+// to build the hash which makes duplicate names unique
+func (fn *fileNamer) Name(name string, id string) string {
+	// Windows drops trailing dots and spaces from file names
+	n := strings.TrimRight(strings.Trim(fn.sanitize(name), "_ "), ". ")
+	if n == "" {
+		n = "attachment"
+	}
+
+	// Dropbox and Windows treat names differing only by case as the same file
+	if fn.used[strings.ToLower(n)] {
+		ext := path.Ext(n)
+		h := sha1.Sum([]byte(id + name))
+		n = fmt.Sprintf("%s_%x%s", strings.TrimSuffix(n, ext), h[:4], ext)
+	}
+
+	fn.used[strings.ToLower(n)] = true
+	return n
+}
@@ -0,0 +1,9 @@
+module github.com/jnormington/trello-to-clubhouse.io
+
+go 1.26.0
+
+require (
+	golang.org/x/crypto v0.57.0
+	golang.org/x/text v0.42.0
+	gopkg.in/yaml.v2 v2.4.0
+)
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// htmlTagRegexp finds the tags pasted rich text (emails, web pages) brings along,
+// text without any of them is left as it is so a literal <tag> in markdown survives
+var htmlTagRegexp = regexp.MustCompile(`(?i)</?(p|br|div|span|a|b|strong|i|em|u|ul|ol|li|h[1-6]|table|tr|td|th|font|blockquote|pre|code|img)\b[^>]*>`)
+
+var (
+	htmlDropRegexp       = regexp.MustCompile(`(?is)<!--.*?-->|<(style|script|head)\b[^>]*>.*?</(style|script|head)>`)
+	htmlLinkRegexp       = regexp.MustCompile(`(?is)<a\b[^>]*?href\s*=\s*["']([^"']*)["'][^>]*>(.*?)</a>`)
+	htmlImageRegexp      = regexp.MustCompile(`(?is)<img\b[^>]*?src\s*=\s*["']([^"']*)["'][^>]*>`)
+	htmlAltRegexp        = regexp.MustCompile(`(?is)\balt\s*=\s*["']([^"']*)["']`)
+	htmlHeadingRegexp    = regexp.MustCompile(`(?i)<h([1-6])\b[^>]*>`)
+	htmlAnyTagRegexp     = regexp.MustCompile(`<[^>]+>`)
+	htmlBlankLinesRegexp = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlTagReplacements turn the formatting tags into their markdown,
+// the closing tags of blocks leave a blank line
+var htmlTagReplacements = []struct {
+	re   *regexp.Regexp
+	with string
+}{
+	{regexp.MustCompile(`(?i)<br\s*/?>`), "\n"},
+	{regexp.MustCompile(`(?i)</(p|div|h[1-6]|table|blockquote|ul|ol)>`), "\n\n"},
+	{regexp.MustCompile(`(?i)<(p|div|table|blockquote|ul|ol)\b[^>]*>`), "\n"},
+	{regexp.MustCompile(`(?i)</tr>`), "\n"},
+	{regexp.MustCompile(`(?i)<(td|th)\b[^>]*>`), " "},
+	{regexp.MustCompile(`(?i)<li\b[^>]*>`), "\n- "},
+	{regexp.MustCompile(`(?i)</?(b|strong)\b[^>]*>`), "**"},
+	{regexp.MustCompile(`(?i)</?(i|em)\b[^>]*>`), "*"},
+	{regexp.MustCompile(`(?i)</?pre\b[^>]*>`), "\n```\n"},
+	{regexp.MustCompile(`(?i)</?code\b[^>]*>`), "`"},
+}
+
+// convertHTML turns pasted html into markdown so clubhouse doesn't show the
+// literal tags, tags without a markdown equivalent are dropped keeping their text
+func convertHTML(s string) string {
+	if !htmlTagRegexp.MatchString(s) {
+		return s
+	}
+
+	s = htmlDropRegexp.ReplaceAllString(s, "")
+
+	s = htmlImageRegexp.ReplaceAllStringFunc(s, func(m string) string {
+		var alt string
+		if a := htmlAltRegexp.FindStringSubmatch(m); a != nil {
+			alt = a[1]
+		}
+
+		return "![" + alt + "](" + htmlImageRegexp.FindStringSubmatch(m)[1] + ")"
+	})
+
+	s = htmlLinkRegexp.ReplaceAllStringFunc(s, func(m string) string {
+		sm := htmlLinkRegexp.FindStringSubmatch(m)
+		text := strings.TrimSpace(htmlAnyTagRegexp.ReplaceAllString(sm[2], ""))
+		if text == "" {
+			text = sm[1]
+		}
+
+		return "[" + text + "](" + sm[1] + ")"
+	})
+
+	s = htmlHeadingRegexp.ReplaceAllStringFunc(s, func(m string) string {
+		return "\n" + strings.Repeat("#", int(m[2]-'0')) + " "
+	})
+
+	for _, r := range htmlTagReplacements {
+		s = r.re.ReplaceAllString(s, r.with)
+	}
+
+	s = html.UnescapeString(htmlAnyTagRegexp.ReplaceAllString(s, ""))
+
+	lines := strings.Split(strings.Replace(s, "\r\n", "\n", -1), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t")
+	}
+
+	return strings.TrimSpace(htmlBlankLinesRegexp.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// cassetteSecretParams are left out of the recorded urls so
+// cassettes can be shared without the tokens
+var cassetteSecretParams = []string{"key", "token"}
+
+// httpInteraction is a request and its response in the cassette, the request body
+// is only kept as a hash so the tokens and files sent aren't recorded
+type httpInteraction struct {
+	Method      string `json:"method"`
+	URL         string `json:"url"`
+	BodySHA256  string `json:"body_sha256,omitempty"`
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body"`
+}
+
+// httpCassette records every http call of the run to a json lines file, or replays
+// the calls from one, so a run can be repeated (pagination and errors included)
+// without live credentials or network
+type httpCassette struct {
+	next   http.RoundTripper
+	record *os.File
+	replay []httpInteraction
+	played []bool
+
+	mu sync.Mutex
+}
+
+// setupHTTPCassette installs the recorder or player as the default transport,
+// the trello, clubhouse and dropbox clients all use it
+func setupHTTPCassette(recordPath string, replayPath string) {
+	c := &httpCassette{next: http.DefaultTransport}
+
+	switch {
+	case recordPath != "":
+		f, err := os.Create(recordPath)
+		if err != nil {
+			fatalConfigf("Error creating the http cassette: %s", err)
+		}
+		c.record = f
+	case replayPath != "":
+		r, err := loadHTTPCassette(replayPath)
+		if err != nil {
+			fatalConfigf("Error loading the http cassette: %s", err)
+		}
+		c.replay, c.played = r, make([]bool, len(r))
+	default:
+		return
+	}
+
+	http.DefaultTransport = c
+}
+
+func loadHTTPCassette(path string) ([]httpInteraction, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r []httpInteraction
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 1024*1024), 1024*1024*1024)
+	for sc.Scan() {
+		var i httpInteraction
+		if err := json.Unmarshal(sc.Bytes(), &i); err != nil {
+			return nil, err
+		}
+
+		r = append(r, i)
+	}
+
+	return r, sc.Err()
+}
+
+func (c *httpCassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	var hash string
+	if len(body) > 0 {
+		h := sha256.Sum256(body)
+		hash = hex.EncodeToString(h[:])
+	}
+
+	if c.replay != nil {
+		return c.play(req, hash)
+	}
+
+	resp, err := c.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+
+	line, _ := json.Marshal(httpInteraction{
+		Method:      req.Method,
+		URL:         cassetteURL(req.URL),
+		BodySHA256:  hash,
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        b,
+	})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.record.Write(append(line, '\n'))
+
+	return resp, nil
+}
+
+// play returns the next recorded response of the same call, preferring
+// one with the same body as bodies holding timestamps change between runs.
+// A call whose query differs, such as the fields the api packages ask for,
+// is answered by the next recorded call of the same path
+func (c *httpCassette) play(req *http.Request, hash string) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	u := cassetteURL(req.URL)
+	path := strings.SplitN(u, "?", 2)[0]
+
+	i := c.find(func(r *httpInteraction) bool { return r.Method == req.Method && r.URL == u && r.BodySHA256 == hash })
+	if i < 0 {
+		i = c.find(func(r *httpInteraction) bool { return r.Method == req.Method && r.URL == u })
+	}
+	if i < 0 {
+		i = c.find(func(r *httpInteraction) bool {
+			return r.Method == req.Method && strings.SplitN(r.URL, "?", 2)[0] == path
+		})
+	}
+	if i < 0 {
+		return nil, fmt.Errorf("no recorded response for %s %s", req.Method, u)
+	}
+
+	c.played[i] = true
+	r := c.replay[i]
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.Status, http.StatusText(r.Status)),
+		StatusCode:    r.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {r.ContentType}},
+		Body:          ioutil.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}, nil
+}
+
+// cassetteURL is the url without the credentials with its query sorted
+func cassetteURL(u *url.URL) string {
+	c := *u
+	q := c.Query()
+	for _, p := range cassetteSecretParams {
+		q.Del(p)
+	}
+	c.RawQuery = q.Encode()
+
+	return c.String()
+}
+
+// find returns the first recorded call not played yet matching, -1 when there is none
+func (c *httpCassette) find(match func(r *httpInteraction) bool) int {
+	for i := range c.replay {
+		if !c.played[i] && match(&c.replay[i]) {
+			return i
+		}
+	}
+
+	return -1
+}
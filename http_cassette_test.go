@@ -0,0 +1,87 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestReplayDryRun runs a non-interactive dry run of testdata/replay/dry_run.jsonl,
+// re-record it with -http-record when the api packages change their calls
+func TestReplayDryRun(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmp, err := ioutil.TempDir("", "replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	// Stored credentials and environment tokens of the machine are never used
+	for k, v := range map[string]string{
+		"HOME":            tmp,
+		"XDG_CONFIG_HOME": tmp,
+		"AppData":         tmp,
+		"TRELLO_KEY":      "test-key",
+		"TRELLO_TOKEN":    "test-token",
+		"CLUBHOUSE_TOKEN": "test-token",
+	} {
+		defer restoreEnv(k)()
+		os.Setenv(k, v)
+	}
+
+	transport, keys := http.DefaultTransport, []*string{&trelloKey, &trelloToken, &clubHouseToken}
+	saved := []string{trelloKey, trelloToken, clubHouseToken}
+	defer func() {
+		http.DefaultTransport, nonInteractive, report = transport, false, newReport()
+		for i, k := range keys {
+			*k = saved[i]
+		}
+	}()
+	trelloKey, trelloToken, clubHouseToken = "YOURKEY", "YOURTOKEN", "YOURTOKEN"
+
+	reportPath := filepath.Join(tmp, "report.json")
+	runMigration(ParseConfig([]string{
+		"-non-interactive",
+		"-dry-run",
+		"-http-replay", filepath.Join(dir, "testdata", "replay", "dry_run.jsonl"),
+		"-mapping", filepath.Join(dir, "testdata", "replay", "mapping.yml"),
+		"-list", "5c7200000000000000000c01",
+		"-import-member", "importer@example.com",
+		"-fallback-creator", fallbackCreatorNone,
+		"-output", "table",
+		"-report", reportPath,
+		"-concurrency", "1",
+	}))
+
+	r, err := loadReport(reportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.Cards) != 2 {
+		t.Fatalf("expected 2 cards in the report got %d", len(r.Cards))
+	}
+	for _, c := range r.Cards {
+		if c.Status != "Would Create" {
+			t.Errorf("card %s has status '%s' expected 'Would Create'", c.CardName, c.Status)
+		}
+	}
+}
+
+// restoreEnv returns a func setting the variable back to its current value
+func restoreEnv(key string) func() {
+	v, ok := os.LookupEnv(key)
+	return func() {
+		if ok {
+			os.Setenv(key, v)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
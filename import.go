@@ -2,115 +2,575 @@ package main
 
 import (
 	"fmt"
+	"mime"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	ch "github.com/jnormington/clubhouse-go"
 )
 
-var outputFormat = "%-40s %-17s %s\n"
+const (
+	urlAttachmentsLinkedFile  = "linked-file"
+	urlAttachmentsDescription = "description"
 
-// ImportCardsIntoClubhouse takes *[]Card, *ClubhouseOptions and builds a clubhouse Story
-// this story from both the card and clubhouse options and creates via the api.
-func ImportCardsIntoClubhouse(cards *[]Card, opts *ClubhouseOptions, um *UserMap) {
+	requestedByCreator      = "creator"
+	requestedByFirstOwner   = "first-owner"
+	requestedByImportMember = "import-member"
+	requestedByFixedMember  = "member"
+
+	defaultOwnerNone    = "none"
+	defaultOwnerCreator = "creator"
+	defaultOwnerMember  = "member"
+
+	dueCompleteKeep      = "keep"
+	dueCompleteClear     = "clear"
+	dueCompleteLabel     = "label"
+	dueCompleteDoneState = "done-state"
+	dueCompleteLabelName = "done-on-time"
+
+	descriptionOverflowComments = "comments"
+	descriptionOverflowTruncate = "truncate"
+
+	commentsEach   = "each"
+	commentsDigest = "digest"
+
+	maxDescriptionLength = 100000
+	maxCommentLength     = 100000
+
+	// Comments over this are added after the story is created
+	// so large threads don't exceed the request limits
+	maxCommentsPerStoryRequest = 100
+
+	// Linked files failing before the story is created are retried this many times
+	maxLinkedFileAttempts = 3
+
+	truncatedNotice = "\n\n---\n*The Trello description was too long for Clubhouse and has been truncated*"
+	continuedNotice = "\n\n---\n*The Trello description was too long for Clubhouse and continues in the comments*"
+)
+
+// ImportCardsIntoClubhouse takes the exported cards as they arrive, builds a clubhouse Story
+// from both the card and clubhouse options and creates it via the api, total is the number
+// of cards being exported. It returns the number of cards which failed to import.
+func ImportCardsIntoClubhouse(cards <-chan Card, total int, opts *ClubhouseOptions, um *UserMap) int {
 	fmt.Println("Importing trello cards into Clubhouse...")
-	fmt.Printf(outputFormat+"\n", "Trello Card Link", "Import Status", "Error/Story ID")
+	cardOutput.Header("Trello Card Link", "Import Status", "Error/Story ID")
 	stories, _ := opts.ClubhouseEntry.ListStories(opts.Project.ID)
 
-	for _, c := range *cards {
-		deleteMatchingStories(stories, opts, c)
+	if opts.Reconcile {
+		opts.StoryMap = reconcileStories(opts, stories)
+	}
+	if opts.StoryMap != nil && opts.StoryLists == nil {
+		opts.StoryLists = map[string]string{}
+	}
+
+	if opts.StoryMap != nil && !opts.DryRun {
+		defer writeStoryMap(opts.StoryMapPath, opts.StoryMap, opts.StoryLists)
+	}
+
+	var succeeded, failed int
+	linker := newStoryLinker(opts.ChecklistLinks)
+
+	var i int
+	var last string
+	for c := range cards {
+		if stopRequested() {
+			// The cards exported ahead are left for the next run
+			break
+		}
+
+		// The previous card is finished so its report can leave memory
+		report.Done(last)
+		last = c.ShortURL
+
+		if opts.ConfirmEvery > 0 && i > 0 && i%opts.ConfirmEvery == 0 {
+			promptUserContinueImport(succeeded, failed, total-i)
+		}
+		i++
+
+		if err := applyTextHook(opts.TextHook, &c); err != nil {
+			report.SetResult(c.ShortURL, c.Name, 0, err)
+			failed++
+			cardOutput.Row(c.ShortURL, "Failed", err)
+			continue
+		}
+
+		if scanCardForSecrets(opts.ScanSecrets, &c) {
+			continue
+		}
+
+		if opts.TemplateCards != templateCardsImport && opts.isTemplateCard(&c) {
+			importTemplateCard(&c, opts)
+			continue
+		}
+
+		if opts.DryRun {
+			dryRunCard(&c, findDuplicateStories(stories, opts, c), opts, um)
+			continue
+		}
+
+		if opts.isEpicCard(&c) {
+			id, err := importCardAsEpic(&c, opts, um)
+			report.SetEpicResult(c.ShortURL, c.Name, id, err)
+			if err != nil {
+				failed++
+				cardOutput.Row(c.ShortURL, "Failed", err)
+				continue
+			}
+
+			appURL := opts.clubhouseAppURL("epic", id)
+			opts.Annotator.Annotate(&c, "epic", id, appURL)
+
+			succeeded++
+			cardOutput.Row(c.ShortURL, "Success", fmt.Sprintf("Epic ID: %d %s", id, appURL))
+			continue
+		}
+
+		dups, skip := chooseDuplicateStories(findDuplicateStories(stories, opts, c), opts, c)
+		if skip {
+			report.SetSkipped(c.ShortURL, c.Name, 0)
+			cardOutput.Row(c.ShortURL, "Skipped", "by choice between duplicates")
+			continue
+		}
+
+		if len(dups) > 0 && opts.OnDuplicate == onDuplicateUpdate {
+			id := dups[0].ID
+			err := retryOnHardLimit(c.ShortURL, func() error { return updateExistingStory(&c, id, opts) })
+			report.SetUpdated(c.ShortURL, c.Name, id, err)
+			linker.Known(c.ShortURL, id)
+			if err != nil {
+				failed++
+				cardOutput.Row(c.ShortURL, "Failed", err)
+				continue
+			}
+			if opts.StoryLists != nil {
+				opts.StoryLists[c.ShortURL] = c.IDList
+			}
+
+			succeeded++
+			cardOutput.Row(c.ShortURL, "Updated", fmt.Sprintf("Story ID: %d %s", id, opts.clubhouseAppURL("story", id)))
+			continue
+		} else if len(dups) > 0 && opts.OnDuplicate == onDuplicateSkip {
+			report.SetSkipped(c.ShortURL, c.Name, dups[0].ID)
+			linker.Known(c.ShortURL, dups[0].ID)
+			cardOutput.Row(c.ShortURL, "Skipped", fmt.Sprintf("Story ID: %d", dups[0].ID))
+			continue
+		}
+
+		// Nothing is changed in clubhouse until the story passed the review
+		cs := storyFromCard(&c, opts, um, nil)
+		if opts.Review != "" && !reviewStory(&c, cs, opts) {
+			report.SetSkipped(c.ShortURL, c.Name, 0)
+			cardOutput.Row(c.ShortURL, "Skipped", "by review")
+			continue
+		}
+
+		replaceDuplicateStories(dups, opts, c)
+
+		var failedFiles []ch.CreateLinkedFile
+		cs.LinkedFileIds, failedFiles = buildLinkFiles(&c, opts)
+		remaining := splitOffComments(cs)
+
 		//We could use bulk update but lets give the user some prompt feedback
-		st, err := opts.ClubhouseEntry.CreateStory(*buildClubhouseStory(&c, opts, um))
+		var st ch.Story
+		err := retryOnHardLimit(c.ShortURL, func() (err error) {
+			st, err = opts.ClubhouseEntry.CreateStory(*cs)
+			return err
+		})
 		if err != nil {
-			fmt.Printf(outputFormat, c.ShortURL, "Failed", err)
+			report.SetResult(c.ShortURL, c.Name, 0, err)
+			failed++
+			cardOutput.Row(c.ShortURL, "Failed", err)
 			continue
 		}
 
-		fmt.Printf(outputFormat, c.ShortURL, "Success", fmt.Sprintf("Story ID: %d", st.ID))
+		report.SetResult(c.ShortURL, c.Name, st.ID, nil)
+		linker.Add(st.ID, &c)
+		if opts.StoryMap != nil {
+			opts.StoryMap[c.ShortURL] = st.ID
+			opts.StoryLists[c.ShortURL] = c.IDList
+		}
+		addRemainingComments(st.ID, remaining, c)
+		cs.LinkedFileIds = retryLinkedFiles(st.ID, cs.LinkedFileIds, failedFiles, c, opts)
+		report.SetExpected(c.ShortURL, c.Name, newExpectedStory(cs, len(remaining)))
+		opts.setPriorityField(st.ID, &c)
+		if opts.ExternalLink {
+			addExternalLink(st.ID, c)
+		}
+
+		if st.AppURL == "" {
+			st.AppURL = opts.clubhouseAppURL("story", st.ID)
+		}
+		opts.Annotator.Annotate(&c, "story", st.ID, st.AppURL)
+
+		succeeded++
+		cardOutput.Row(c.ShortURL, "Success", fmt.Sprintf("Story ID: %d %s", st.ID, st.AppURL))
 	}
+
+	report.Done(last)
+	linker.Link(opts)
+
+	return failed
 }
 
-func deleteMatchingStories(stories []ch.Story, opts *ClubhouseOptions, card Card) {
-	//delete story if already exists
-	for i := 0; i < len(stories); i++ {
-		if stories[i].Name == card.Name {
-			err := opts.ClubhouseEntry.DeleteStory(stories[i].ID)
-			if err != nil {
-				fmt.Printf(outputFormat, card.ShortURL, "Deleted Matching", fmt.Sprintf("Story ID: %d", stories[i].ID))
-			}
+func splitOffComments(cs *ch.CreateStory) []ch.CreateComment {
+	if len(cs.Comments) <= maxCommentsPerStoryRequest {
+		return nil
+	}
+
+	remaining := cs.Comments[maxCommentsPerStoryRequest:]
+	cs.Comments = cs.Comments[:maxCommentsPerStoryRequest]
+
+	return remaining
+}
+
+func addRemainingComments(storyID int64, comments []ch.CreateComment, card Card) {
+	for _, cm := range comments {
+		err := clubhouseRequest("POST", fmt.Sprintf("/stories/%d/comments", storyID), cm, nil)
+		if err != nil {
+			fmt.Println("Error: Adding comment to story for card:", card.Name, "ignoring...", err)
 		}
 	}
 }
 
-func buildLinkFiles(card *Card, opts *ClubhouseOptions) []int64 {
+// importTemplateCard skips the template card or adds it as a story template
+func importTemplateCard(c *Card, opts *ClubhouseOptions) {
+	if opts.TemplateCards == templateCardsSkip || opts.DryRun {
+		report.SetSkipped(c.ShortURL, c.Name, 0)
+		cardOutput.Row(c.ShortURL, "Skipped", "template card")
+		return
+	}
+
+	id, err := createStoryTemplate(c, opts)
+	report.SetTemplateResult(c.ShortURL, c.Name, id, err)
+	if err != nil {
+		cardOutput.Row(c.ShortURL, "Failed", err)
+		return
+	}
+
+	cardOutput.Row(c.ShortURL, "Success", fmt.Sprintf("Template ID: %s", id))
+}
+
+// addExternalLink sets the trello card as an external link of the story,
+// clubhouse shows these in the story sidebar
+func addExternalLink(storyID int64, card Card) {
+	body := map[string][]string{"external_links": {card.ShortURL}}
+	err := clubhouseRequest("PUT", fmt.Sprintf("/stories/%d", storyID), body, nil)
+	if err != nil {
+		fmt.Println("Error: Adding the trello link to story for card:", card.Name, "ignoring...", err)
+	}
+}
+
+func promptUserContinueImport(succeeded int, failed int, remaining int) {
+	fmt.Printf("\nImported so far\n\tSuccess: %d\n\tFailed: %d\n\tRemaining: %d\n\n", succeeded, failed, remaining)
+	fmt.Println("Would you like to continue importing ?")
+
+	for i, o := range yesNoOpts {
+		fmt.Printf("[%d] %s\n", i, o)
+	}
+
+	if promptUserSelectResource() != 0 {
+		abortRun("Stopping user aborted the import")
+	}
+}
+
+// buildLinkFiles creates the linked files for the dropbox attachments and url links,
+// the ones failing are returned to retry once the story has been created
+func buildLinkFiles(card *Card, opts *ClubhouseOptions) ([]int64, []ch.CreateLinkedFile) {
 	ids := []int64{}
+	var failed []ch.CreateLinkedFile
+
+	files := []ch.CreateLinkedFile{}
+	for _, k := range sortedKeys(card.Attachments) {
+		files = append(files, ch.CreateLinkedFile{Name: k, Type: "dropbox", URL: card.Attachments[k], UploaderID: opts.ImportMember.ID})
+	}
 
-	for k, v := range card.Attachments {
-		lf := ch.CreateLinkedFile{
-			Name:       k,
-			Type:       "dropbox",
-			URL:        v,
-			UploaderID: opts.ImportMember.ID,
+	if opts.URLAttachments == urlAttachmentsLinkedFile {
+		for _, k := range sortedKeys(card.Links) {
+			files = append(files, ch.CreateLinkedFile{Name: k, Type: "url", URL: card.Links[k], UploaderID: opts.ImportMember.ID})
 		}
+	}
 
+	for _, lf := range files {
 		r, err := opts.ClubhouseEntry.CreateLinkedFiles(lf)
 		if err != nil {
-			fmt.Println("Fail to create linked file card name:", card.Name, "Dropbox link:", v, "Err:", err)
-		} else {
-			ids = append(ids, r.ID)
+			fmt.Println("Fail to create linked file card name:", card.Name, "Link:", lf.URL, "Err:", err, "retrying after the story is created...")
+			failed = append(failed, lf)
+			continue
 		}
+
+		ids = append(ids, r.ID)
+	}
+
+	return ids, failed
+}
+
+// retryLinkedFiles creates the linked files which failed before the story
+// was created and adds them to the story, the ones still failing are
+// recorded in the report. It returns the linked files of the story
+func retryLinkedFiles(storyID int64, linked []int64, failed []ch.CreateLinkedFile, card Card, opts *ClubhouseOptions) []int64 {
+	if len(failed) == 0 {
+		return linked
+	}
+
+	ids := append([]int64{}, linked...)
+	for _, lf := range failed {
+		var r ch.LinkedFile
+		var err error
+
+		for attempt := 1; attempt <= maxLinkedFileAttempts; attempt++ {
+			time.Sleep(time.Duration(attempt) * time.Second)
+			if r, err = opts.ClubhouseEntry.CreateLinkedFiles(lf); err == nil {
+				break
+			}
+		}
+
+		if err != nil {
+			fmt.Println("Error: Creating linked file for card:", card.Name, "Link:", lf.URL, "giving up...", err)
+			report.AddFailedLink(card.ShortURL, card.Name, lf.URL)
+			continue
+		}
+
+		ids = append(ids, r.ID)
+	}
+
+	if len(ids) == len(linked) {
+		return linked
+	}
+
+	body := map[string][]int64{"linked_file_ids": ids}
+	if err := clubhouseRequest("PUT", fmt.Sprintf("/stories/%d", storyID), body, nil); err != nil {
+		fmt.Println("Error: Adding the retried linked files to story for card:", card.Name, "ignoring...", err)
+		for _, lf := range failed {
+			report.AddFailedLink(card.ShortURL, card.Name, lf.URL)
+		}
+		return linked
 	}
 
 	return ids
 }
 
-func buildClubhouseStory(card *Card, opts *ClubhouseOptions, um *UserMap) *ch.CreateStory {
+func buildDescription(card *Card, opts *ClubhouseOptions) string {
+	d := card.Desc + opts.createdAtFooter(card.CreatedAt, "\n\n---\n*Originally created in Trello %s*")
+
+	if opts.InlineImages {
+		d += buildInlineImages(card)
+	}
+
+	if opts.MetadataFooter {
+		d += buildMetadataFooter(card)
+	}
+
+	if opts.URLAttachments == urlAttachmentsDescription && len(card.Links) > 0 {
+		d += "\n\n**Links**\n"
+		for _, k := range sortedKeys(card.Links) {
+			d += fmt.Sprintf("- [%s](%s)\n", k, card.Links[k])
+		}
+	}
+
+	return d
+}
+
+// buildDescriptionWithOverflow truncates descriptions over the Clubhouse limit
+// returning the rest as comments, unless truncating was selected
+func buildDescriptionWithOverflow(card *Card, opts *ClubhouseOptions) (string, []ch.CreateComment) {
+	d := buildDescription(card, opts)
+	comments := []ch.CreateComment{}
+
+	if utf8.RuneCountInString(d) <= maxDescriptionLength {
+		return d, comments
+	}
+
+	parts := splitText(d, maxDescriptionLength-utf8.RuneCountInString(continuedNotice))
+	fmt.Println("Warning: Description too long for:", card.Name, "it has been truncated")
+
+	if opts.DescriptionOverflow == descriptionOverflowTruncate {
+		report.AddDataLoss(lossDescriptions, card.ShortURL, card.Name, fmt.Sprintf("%d parts dropped", len(parts)-1))
+		return parts[0] + truncatedNotice, comments
+	}
+
+	createdAt := opts.commentTime(card.CreatedAt)
+
+	for i, p := range parts[1:] {
+		comments = append(comments, ch.CreateComment{
+			// Keep the parts in order before any of the card comments
+			CreatedAt: createdAt.Add(time.Duration(i) * time.Millisecond),
+			Text:      fmt.Sprintf("*Description continued (%d/%d)*\n\n%s", i+1, len(parts)-1, p),
+		})
+	}
+
+	return parts[0] + continuedNotice, comments
+}
+
+// buildMetadataFooter keeps the stickers and badges which
+// gave the board meaning at a glance
+func buildMetadataFooter(card *Card) string {
+	b := card.Badges
+	badges := []string{fmt.Sprintf("%d attachments", b.Attachments), fmt.Sprintf("%d comments", b.Comments)}
+
+	if b.CheckItems > 0 {
+		badges = append(badges, fmt.Sprintf("checklist %d/%d", b.CheckItemsChecked, b.CheckItems))
+	}
+
+	if b.Votes > 0 {
+		badges = append(badges, fmt.Sprintf("%d votes", b.Votes))
+	}
+
+	if b.Description {
+		badges = append(badges, "has description")
+	}
+
+	d := "\n\n---\n**Trello badges:** " + strings.Join(badges, ", ")
+	if len(card.Stickers) > 0 {
+		d += "\n**Trello stickers:** " + strings.Join(card.Stickers, ", ")
+	}
+
+	return d
+}
+
+func buildInlineImages(card *Card) string {
+	var d string
+
+	for _, k := range sortedKeys(card.Attachments) {
+		if !strings.HasPrefix(mime.TypeByExtension(strings.ToLower(path.Ext(k))), "image/") {
+			continue
+		}
+
+		d += fmt.Sprintf("\n\n![%s](%s)", k, dropboxRawURL(card.Attachments[k]))
+	}
+
+	return d
+}
+
+// dropboxRawURL changes a shared link so it returns the file itself
+// rather than the dropbox preview page, which markdown images need
+func dropboxRawURL(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+
+	q := u.Query()
+	q.Del("dl")
+	q.Set("raw", "1")
+	u.RawQuery = q.Encode()
 
-	return &ch.CreateStory{
+	return u.String()
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// storyFromCard maps the exported card to its story without calling either api,
+// the linked files are created beforehand and passed in by their ids
+func storyFromCard(card *Card, opts *ClubhouseOptions, um *UserMap, linked []int64) *ch.CreateStory {
+	desc, overflow := buildDescriptionWithOverflow(card, opts)
+	comments := keepCommentOrder(append(overflow, *buildComments(card, opts, um)...))
+
+	cs := &ch.CreateStory{
 		ProjectID:       opts.Project.ID,
 		WorkflowStateID: opts.State.ID,
-		RequestedByID:   um.GetCreator(card.IDCreator),
-		OwnerIds:        mapOwnersFromTrelloCard(card, um),
-		StoryType:       opts.StoryType,
+		RequestedByID:   requestedByFromTrelloCard(card, opts, um),
+		OwnerIds:        mapOwnersFromTrelloCard(card, opts, um),
+		StoryType:       opts.storyTypeFor(card),
 		FollowerIds:     []string{},
 		FileIds:         []int64{},
 
 		Name:        card.Name,
-		Description: card.Desc,
-		Deadline:    card.DueDate,
-		CreatedAt:   card.CreatedAt,
+		Description: desc,
+		Deadline:    opts.deadline(card.DueDate),
+		CreatedAt:   opts.storyTime(card.CreatedAt),
+		ExternalID:  card.ID,
+
+		Labels:   *buildLabels(card, opts),
+		Tasks:    *buildTasks(card, opts, um),
+		Comments: comments,
 
-		Labels:   *buildLabels(card),
-		Tasks:    *buildTasks(card),
-		Comments: *buildComments(card, opts.AddCommentWithTrelloLink, um),
+		LinkedFileIds: linked,
+	}
+
+	applyDueComplete(cs, card, opts)
+	return cs
+}
+
+// applyDueComplete stops completed due dates importing as stale deadlines
+func applyDueComplete(cs *ch.CreateStory, card *Card, opts *ClubhouseOptions) {
+	if !card.DueComplete || card.DueDate == nil {
+		return
+	}
+
+	switch opts.DueComplete {
+	case dueCompleteClear:
+		cs.Deadline = nil
+	case dueCompleteLabel:
+		cs.Labels = append(cs.Labels, ch.CreateLabel{Name: opts.namespacedLabel(dueCompleteLabelName)})
+	case dueCompleteDoneState:
+		if opts.DoneState != nil {
+			cs.WorkflowStateID = opts.DoneState.ID
+		}
+	}
+}
 
-		LinkedFileIds: buildLinkFiles(card, opts),
+func requestedByFromTrelloCard(c *Card, opts *ClubhouseOptions, um *UserMap) string {
+	switch opts.RequestedBy {
+	case requestedByFirstOwner:
+		if len(c.IDOwners) > 0 {
+			return um.GetCreator(c.IDOwners[0])
+		}
+	case requestedByImportMember:
+		return opts.ImportMember.ID
+	case requestedByFixedMember:
+		return opts.RequestedByMember.ID
 	}
+
+	return um.GetCreator(c.IDCreator)
 }
 
-func mapOwnersFromTrelloCard(c *Card, um *UserMap) []string {
+// mapOwnersFromTrelloCard maps the card members, a card without
+// members is owned by the default owner so the story isn't left unowned
+func mapOwnersFromTrelloCard(c *Card, opts *ClubhouseOptions, um *UserMap) []string {
 	owners := []string{}
 
 	for _, o := range c.IDOwners {
 		owners = append(owners, um.GetCreator(o))
 	}
 
-	return owners
-}
-
-func buildComments(card *Card, addCommentWithTrelloLink bool, um *UserMap) *[]ch.CreateComment {
-	comments := []ch.CreateComment{}
+	if len(owners) > 0 {
+		return owners
+	}
 
-	for _, cm := range card.Comments {
-		com := ch.CreateComment{
-			CreatedAt: *cm.CreatedAt,
-			AuthorID:  um.GetCreator(cm.IDCreator),
-			Text:      cm.Text,
+	switch opts.DefaultOwner {
+	case defaultOwnerCreator:
+		if u := um.GetCreator(c.IDCreator); u != "" {
+			owners = append(owners, u)
 		}
+	case defaultOwnerMember:
+		owners = append(owners, opts.DefaultOwnerMember.ID)
+	}
 
-		comments = append(comments, com)
+	return owners
+}
+
+// buildComments adds the comments oldest first, merged into one with -comments=digest
+func buildComments(card *Card, opts *ClubhouseOptions, um *UserMap) *[]ch.CreateComment {
+	var comments []ch.CreateComment
+	if opts.CommentsMode == commentsDigest {
+		comments = buildDigestComment(card, opts, um)
+	} else {
+		comments = buildEachComment(card, opts, um)
 	}
 
-	if addCommentWithTrelloLink {
+	if opts.AddCommentWithTrelloLink {
 		cc := ch.CreateComment{
 			CreatedAt: time.Now(),
 			Text:      fmt.Sprintf("Card imported from Trello: %s", card.ShortURL),
@@ -122,13 +582,113 @@ func buildComments(card *Card, addCommentWithTrelloLink bool, um *UserMap) *[]ch
 	return &comments
 }
 
-func buildTasks(card *Card) *[]ch.CreateTask {
+// buildEachComment adds each trello comment as a comment by its author
+func buildEachComment(card *Card, opts *ClubhouseOptions, um *UserMap) []ch.CreateComment {
+	comments := []ch.CreateComment{}
+
+	// The trello actions are newest first
+	for j := len(card.Comments) - 1; j >= 0; j-- {
+		cm := card.Comments[j]
+		text := cm.Text + opts.createdAtFooter(cm.CreatedAt, "\n\n*Originally posted in Trello %s*")
+
+		author := um.GetCreator(cm.IDCreator)
+		if opts.CommentAuthorFallback {
+			author = opts.TokenMemberID
+			text = fmt.Sprintf("*%s wrote:*\n\n%s", cm.CreatorName, text)
+		}
+
+		// Leave room for the part marker
+		parts := splitText(text, maxCommentLength-32)
+
+		for i, p := range parts {
+			com := ch.CreateComment{
+				// Offset the parts so they stay in order
+				CreatedAt: opts.commentTime(cm.CreatedAt).Add(time.Duration(i) * time.Millisecond),
+				AuthorID:  author,
+				Text:      p,
+			}
+
+			if len(parts) > 1 {
+				com.Text = fmt.Sprintf("%s\n\n*(%d/%d)*", p, i+1, len(parts))
+			}
+
+			comments = append(comments, com)
+		}
+	}
+
+	return comments
+}
+
+// buildDigestComment merges the trello comments oldest first into one comment by
+// the import member dated as the newest, split in parts when over the limit
+func buildDigestComment(card *Card, opts *ClubhouseOptions, um *UserMap) []ch.CreateComment {
+	if len(card.Comments) == 0 {
+		return []ch.CreateComment{}
+	}
+
+	var b strings.Builder
+	for j := len(card.Comments) - 1; j >= 0; j-- {
+		cm := card.Comments[j]
+
+		date := "unknown date"
+		if cm.CreatedAt != nil {
+			date = cm.CreatedAt.UTC().Format(minCreatedAtLayout)
+		}
+
+		fmt.Fprintf(&b, "**%s** (%s): %s\n\n", cm.CreatorName, date, cm.Text)
+	}
+
+	author := um.BackupUserID
+	if opts.CommentAuthorFallback {
+		author = opts.TokenMemberID
+	}
+
+	parts := splitText(strings.TrimSpace(b.String()), maxCommentLength-32)
+	comments := make([]ch.CreateComment, 0, len(parts))
+
+	for i, p := range parts {
+		com := ch.CreateComment{
+			CreatedAt: opts.commentTime(card.Comments[0].CreatedAt).Add(time.Duration(i) * time.Millisecond),
+			AuthorID:  author,
+			Text:      p,
+		}
+
+		if len(parts) > 1 {
+			com.Text = fmt.Sprintf("%s\n\n*(%d/%d)*", p, i+1, len(parts))
+		}
+
+		comments = append(comments, com)
+	}
+
+	return comments
+}
+
+// keepCommentOrder offsets comments which don't come after the previous one, clubhouse
+// orders comments by their timestamp so comments posted in the same second or clamped
+// to the same date would otherwise lose their reading order
+func keepCommentOrder(comments []ch.CreateComment) []ch.CreateComment {
+	for i := 1; i < len(comments); i++ {
+		if prev := comments[i-1].CreatedAt; !comments[i].CreatedAt.After(prev) {
+			comments[i].CreatedAt = prev.Add(time.Millisecond)
+		}
+	}
+
+	return comments
+}
+
+func buildTasks(card *Card, opts *ClubhouseOptions, um *UserMap) *[]ch.CreateTask {
 	tasks := []ch.CreateTask{}
 
 	for _, t := range card.Tasks {
+		if opts.ChecklistLinks != "" && checklistLinkURL(t) != "" {
+			// Becomes a story link once the referenced card is imported
+			continue
+		}
+
 		ts := ch.CreateTask{
 			Complete:    t.Completed,
-			Description: t.Description,
+			Description: taskDescription(t),
+			OwnerIds:    mapTaskOwner(t, um),
 		}
 
 		tasks = append(tasks, ts)
@@ -137,12 +697,52 @@ func buildTasks(card *Card) *[]ch.CreateTask {
 	return &tasks
 }
 
-func buildLabels(card *Card) *[]ch.CreateLabel {
+// taskDescription adds the due date of an advanced checklist item
+// as clubhouse tasks have no due date of their own
+func taskDescription(t Task) string {
+	if t.DueDate == nil {
+		return t.Description
+	}
+
+	return fmt.Sprintf("%s (due %s)", t.Description, t.DueDate.Format("2006-01-02"))
+}
+
+// mapTaskOwner returns the clubhouse member of the checklist item assignee,
+// unlike the story owners an unmapped assignee leaves the task unowned
+func mapTaskOwner(t Task, um *UserMap) []string {
+	// Only members mapped by email resolved to a clubhouse user, the
+	// others fall back to the import member who shouldn't own their tasks
+	if u := um.Mapping[t.IDOwner]; t.IDOwner != "" && u != "" && um.Emails[t.IDOwner] != "" {
+		return []string{u}
+	}
+
+	return []string{}
+}
+
+func buildLabels(card *Card, opts *ClubhouseOptions) *[]ch.CreateLabel {
 	labels := []ch.CreateLabel{}
 
 	for _, l := range card.Labels {
+		if l = opts.mapLabel(l); l != "" {
+			labels = append(labels, ch.CreateLabel{Name: l})
+		}
+	}
+
+	if l := opts.CoverLabels[card.CoverColor]; card.CoverColor != "" && l != "" {
 		labels = append(labels, ch.CreateLabel{Name: l})
 	}
 
+	if p := opts.priorityFor(card); p != "" && opts.PriorityField == nil {
+		labels = append(labels, ch.CreateLabel{Name: p})
+	}
+
+	if l := createdYearLabel(card.CreatedAt); opts.CreatedYearLabels && l != "" {
+		labels = append(labels, ch.CreateLabel{Name: l})
+	}
+
+	for i := range labels {
+		labels[i].Name = opts.namespacedLabel(labels[i].Name)
+	}
+
 	return &labels
 }
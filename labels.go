@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	ch "github.com/jnormington/clubhouse-go"
+	trello "github.com/jnormington/go-trello"
+)
+
+type clubhouseLabel struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// mapLabel returns the clubhouse name of a trello label using the
+// mapping file, empty when the mapping drops the label
+func (co *ClubhouseOptions) mapLabel(l string) string {
+	if n, ok := co.LabelMap[normalizeName(l)]; ok {
+		// Blank in the mapping file drops the label
+		return n
+	}
+
+	return l
+}
+
+// namespacedLabel prefixes the label with the -label-namespace so the imported
+// labels can be found and bulk edited in clubhouse, empty stays empty
+func (co *ClubhouseOptions) namespacedLabel(l string) string {
+	if l == "" || strings.HasPrefix(l, co.LabelNamespace) {
+		return l
+	}
+
+	return co.LabelNamespace + l
+}
+
+// neededLabels is every clubhouse label the cards can be given, the labels
+// only known once a card is exported (cover color, due complete) are included
+// when they are configured
+func neededLabels(cards []trello.Card, to *TrelloOptions, co *ClubhouseOptions) []string {
+	seen := map[string]bool{}
+	var names []string
+
+	add := func(n string) {
+		n = co.namespacedLabel(n)
+		if n != "" && !seen[strings.ToLower(n)] {
+			seen[strings.ToLower(n)] = true
+			names = append(names, n)
+		}
+	}
+
+	for _, c := range cards {
+		for _, l := range c.Labels {
+			add(co.mapLabel(labelName(l.Name, l.Color, to.ColorLabels)))
+		}
+
+		if co.CreatedYearLabels {
+			add(createdYearLabel(createdAtFromID(c.Id)))
+		}
+	}
+
+	for _, l := range co.CoverLabels {
+		add(l)
+	}
+
+	if co.PriorityField == nil {
+		for _, p := range co.Priorities {
+			add(p)
+		}
+	}
+
+	if co.DueComplete == dueCompleteLabel {
+		add(dueCompleteLabelName)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// precreateLabels creates the missing labels in one phase before the import,
+// the stories then reference the existing labels by name rather than each
+// story creating the labels it uses
+func precreateLabels(cards []trello.Card, to *TrelloOptions, co *ClubhouseOptions) {
+	names := neededLabels(cards, to, co)
+	if len(names) == 0 {
+		return
+	}
+
+	var existing []clubhouseLabel
+	if err := clubhouseRequest("GET", "/labels", nil, &existing); err != nil {
+		fmt.Println("Error: Querying the clubhouse labels, they are created with the stories instead...", err)
+		return
+	}
+
+	have := map[string]bool{}
+	for _, l := range existing {
+		have[strings.ToLower(l.Name)] = true
+	}
+
+	var missing []string
+	for _, n := range names {
+		if !have[strings.ToLower(n)] {
+			missing = append(missing, n)
+		}
+	}
+
+	fmt.Printf("Creating %d of the %d labels needed in Clubhouse...\n", len(missing), len(names))
+	for i, n := range missing {
+		err := retryOnHardLimit(n, func() error {
+			return clubhouseRequest("POST", "/labels", ch.CreateLabel{Name: n}, nil)
+		})
+
+		if err != nil {
+			fmt.Printf("[%d/%d] Error: Creating label: %s it is created with the stories instead... %s\n", i+1, len(missing), n, err)
+			continue
+		}
+
+		fmt.Printf("[%d/%d] Created label: %s\n", i+1, len(missing), n)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+
+	ch "github.com/jnormington/clubhouse-go"
+)
+
+// runLookupCommand resolves a trello card url to its migrated story, or a
+// story id to its trello card, using the trello card id kept as external id
+func runLookupCommand(args []string) {
+	fs := flag.NewFlagSet("lookup", flag.ExitOnError)
+	project := fs.String("project", "", "name or id of the clubhouse project to search, all projects otherwise")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: trello-to-clubhouse lookup [-project name] <trello card url | story id>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	applyTokens()
+
+	q := fs.Arg(0)
+	if id, err := strconv.ParseInt(q, 10, 64); err == nil {
+		lookupTrelloCard(id)
+		return
+	}
+
+	lookupStory(q, *project)
+}
+
+func lookupTrelloCard(storyID int64) {
+	var st struct {
+		ExternalID string `json:"external_id"`
+		AppURL     string `json:"app_url"`
+	}
+
+	if err := clubhouseRequest("GET", fmt.Sprintf("/stories/%d", storyID), nil, &st); err != nil {
+		log.Fatalf("Error fetching story %d: %s", storyID, err)
+	}
+
+	if st.ExternalID == "" {
+		log.Fatalf("Story %d has no external id, it wasn't imported from trello or predates the external ids", storyID)
+	}
+
+	var card struct {
+		ShortURL string `json:"shortUrl"`
+	}
+
+	if err := trelloRequest("GET", "/cards/"+st.ExternalID, url.Values{"fields": {"shortUrl"}}, &card); err != nil {
+		log.Fatalf("Error fetching trello card %s: %s", st.ExternalID, err)
+	}
+
+	fmt.Printf("%s -> %s\n", st.AppURL, card.ShortURL)
+}
+
+func lookupStory(cardURL string, project string) {
+	m := trelloShortURLRegexp.FindStringSubmatch(cardURL)
+	if m == nil {
+		log.Fatalf("'%s' is not a trello card url or story id", cardURL)
+	}
+
+	var card struct {
+		ID string `json:"id"`
+	}
+
+	if err := trelloRequest("GET", "/cards/"+m[1], url.Values{"fields": {"id"}}, &card); err != nil {
+		log.Fatalf("Error fetching trello card %s: %s", cardURL, err)
+	}
+
+	c := ch.New(clubHouseToken)
+	projects, err := c.ListProjects()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var found bool
+	for _, p := range projects {
+		if project != "" && !sameName(p.Name, project) && strconv.FormatInt(p.ID, 10) != project {
+			continue
+		}
+
+		stories, err := c.ListStories(p.ID)
+		if err != nil {
+			fmt.Println("Error: Listing stories for project:", p.Name, "ignoring...", err)
+			continue
+		}
+
+		for _, st := range stories {
+			if st.ExternalID == card.ID {
+				found = true
+				fmt.Printf("%s -> %s (story %d in %s)\n", cardURL, st.AppURL, st.ID, p.Name)
+			}
+		}
+	}
+
+	if !found {
+		log.Fatalf("No story found with the external id %s of %s", card.ID, cardURL)
+	}
+}
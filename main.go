@@ -8,40 +8,162 @@ import (
 )
 
 var (
-	clubHouseToken 	= "YOURTOKEN"
-	trelloToken    	= "YOURTOKEN"
-	trelloKey      	= "YOURKEY"
-	dropboxToken	= "YOURTOKEN"
-
-	stdinReader   = bufio.NewReader(os.Stdin)
-	errOutOfRange = "Number input is out of range. Try again"
-	yesNoOpts     = []string{"Yes", "No"}
+	clubHouseToken = "YOURTOKEN"
+	trelloToken    = "YOURTOKEN"
+	trelloKey      = "YOURKEY"
+	dropboxToken   = "YOURTOKEN"
+
+	stdinReader    = bufio.NewReader(os.Stdin)
+	nonInteractive bool
+	errOutOfRange  = "Number input is out of range. Try again"
+	yesNoOpts      = []string{"Yes", "No"}
 )
 
 func main() {
-	to := SetupTrelloOptionsFromUser()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "auth":
+			runAuthCommand(os.Args[2:])
+			return
+		case "mapping":
+			runMappingCommand(os.Args[2:])
+			return
+		case "verify":
+			runVerifyCommand(os.Args[2:])
+			return
+		case "lookup":
+			runLookupCommand(os.Args[2:])
+			return
+		case "cleanup":
+			runCleanupCommand(os.Args[2:])
+			return
+		case "decrypt":
+			runDecryptCommand(os.Args[2:])
+			return
+		case "promote":
+			runPromoteCommand(os.Args[2:])
+			return
+		case "stats":
+			runStatsCommand(os.Args[2:])
+			return
+		}
+	}
+
+	runMigration(ParseConfig(os.Args[1:]))
+}
+
+func runMigration(cfg *Config) {
+	setupOutput(cfg.Output)
+	setupHTTPCassette(cfg.HTTPRecord, cfg.HTTPReplay)
+	applyStoredCredentials()
+	sandbox := loadSandboxReport(cfg.DeleteSandbox)
+
+	var m *Mapping
+	if cfg.Mapping != "" {
+		m = LoadMapping(cfg.Mapping)
+		m.applyToConfig(cfg)
+	}
+
+	to := SetupTrelloOptionsFromUser(cfg)
+	if cfg.DryRun {
+		// Nothing is uploaded to dropbox in a dry run
+		to.Attachments.Enabled = false
+	}
+
+	c := sampleCards(to.getCards(), cfg.Sample, cfg.Limit)
+	if cfg.PickCards {
+		c = pickCards(c)
+	}
+	if cfg.ExpandCardLinks {
+		to.CardTitles = newCardTitles(c)
+	}
+	getBoardAutomations(to.Board.Id)
 
-	c := to.getCards()
+	if m != nil && cfg.State == "" {
+		cfg.State = m.StateForList(to.List.Name, to.List.Id)
+	}
 
-	cards := ProcessCardsForExporting(&c, to)
+	if m != nil && cfg.StoryType == "" {
+		cfg.StoryType = m.StoryTypeForList(to.List.Name, to.List.Id)
+	}
 
-	co := SetupClubhouseOptions()
+	co := SetupClubhouseOptions(cfg)
+	// Showing the images inline is asked with the attachments
+	co.InlineImages = to.Attachments.InlineImages
 	um := NewUserMap(to, co)
-	um.SetupUserMapping()
 
+	if m != nil {
+		co.LabelMap = m.LabelMap()
+		um.buildUserMapFromMapping(m)
+	} else {
+		um.SetupUserMapping()
+	}
+
+	checkMembership(to.activeMembers(&c), um, cfg.InviteMissing && !cfg.DryRun)
+	checkDropboxQuota(cfg.DropboxQuota, c, to)
+	if cfg.DryRun {
+		estimateAttachments(c, to)
+	}
 	confirmAllOptionsBeforeImport(to, co)
+	if !cfg.DryRun {
+		co.Annotator = newCardAnnotator(cfg.AnnotateCards, cfg.MigratedLabel, cfg.ArchiveSourceCards, to.Board.Id)
+	}
+
+	// Ctrl+C during the questions still exits straight away
+	watchStopSignals()
+
+	if cfg.Daemon {
+		runDaemon(cfg, to, co, um)
+		return
+	}
+
+	if !cfg.DryRun {
+		precreateLabels(c, to, co)
+	}
+
+	report.Spill(cfg.Report)
+	importBoardIntro(cfg.BoardIntro, to, co)
+	cards := ExportCards(&c, to)
+	if cfg.SaveExport != "" {
+		cards = saveExportedCards(cfg.SaveExport, cards)
+	}
+	failed := ImportCardsIntoClubhouse(cards, len(c), co, um)
+	report.Write(cfg.Report)
+	report.PrintDataLoss()
+
+	if stopRequested() {
+		fmt.Println("*** Stopped before all the cards were imported, run again with -on-duplicate=skip to import the rest ***")
+		os.Exit(exitAborted)
+	}
+
+	if cfg.Verify && !cfg.DryRun {
+		// The finished cards were released from memory during the run
+		r, err := loadReport(cfg.Report)
+		if err != nil {
+			log.Fatalf("Error reading migration report: %s", err)
+		}
+		writeAuditReport(verifyReport(r), defaultAuditFile)
+	}
 
-	ImportCardsIntoClubhouse(cards, co, um)
+	if failed > 0 {
+		fmt.Printf("*** Finished with %d cards which failed to import, see the migration report ***\n", failed)
+		os.Exit(exitFailures)
+	}
+	deleteSandboxStories(sandbox, co)
 	fmt.Println("*** Looks like we finished go and have fun & joy with Clubhouse ***")
 }
 
 func confirmAllOptionsBeforeImport(to *TrelloOptions, co *ClubhouseOptions) {
 	fmt.Println("****** WARNING ******")
 	fmt.Println("Please review carefully before you continue")
-	fmt.Printf("\nExport cards from Trello\n\tBoard: %s\n\tList: %s\n\n\n", to.Board.Name, to.List.Name)
+	fmt.Printf("\nExport cards from Trello\n\tBoard: %s\n\tList: %s\n\tMigrate Attachments: %s\n\n\n", to.Board.Name, to.List.Name, to.Attachments)
 	fmt.Printf("Import cards into clubhouse\n\tProject: %s\n\tWorkflow State: %s\n\tStory Type: %s\n\tAdd Comment with Trello Link: %t\n\n",
 		co.Project.Name, co.State.Name, co.StoryType, co.AddCommentWithTrelloLink)
 
+	if nonInteractive {
+		return
+	}
+
 	fmt.Println("Is the above correct select the number representing your answer ?")
 
 	for i, o := range yesNoOpts {
@@ -51,6 +173,6 @@ func confirmAllOptionsBeforeImport(to *TrelloOptions, co *ClubhouseOptions) {
 	i := promptUserSelectResource()
 
 	if i != 0 {
-		log.Fatal("Stopping user aborted at confirmation step")
+		abortRun("Stopping user aborted at confirmation step")
 	}
 }
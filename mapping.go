@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+
+	ch "github.com/jnormington/clubhouse-go"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const defaultMappingFile = "mapping.yml"
+
+// Mapping is the reviewable mapping file of trello lists, labels
+// and members to their clubhouse workflow states, labels and members
+type Mapping struct {
+	Board   string          `yaml:"board"`
+	Project string          `yaml:"project"`
+	Lists   []ListMapping   `yaml:"lists"`
+	Labels  []LabelMapping  `yaml:"labels"`
+	Members []MemberMapping `yaml:"members"`
+}
+
+// ListMapping maps a trello list to a clubhouse workflow state,
+// the story type overrides the selected one for the cards of the list
+type ListMapping struct {
+	Trello         string `yaml:"trello"`
+	TrelloID       string `yaml:"trello_id"`
+	ClubhouseState string `yaml:"clubhouse_state"`
+	StoryType      string `yaml:"story_type,omitempty"`
+}
+
+// LabelMapping maps a trello label to a clubhouse label,
+// a blank clubhouse label drops the label
+type LabelMapping struct {
+	Trello    string `yaml:"trello"`
+	Clubhouse string `yaml:"clubhouse"`
+}
+
+// MemberMapping maps a trello username to a clubhouse email
+type MemberMapping struct {
+	Trello    string `yaml:"trello"`
+	Clubhouse string `yaml:"clubhouse"`
+}
+
+// runMappingCommand handles mapping generate and mapping apply
+func runMappingCommand(args []string) {
+	if len(args) == 0 {
+		log.Fatal("Expected mapping generate or mapping apply")
+	}
+
+	switch args[0] {
+	case "generate":
+		generateMappingFile(args[1:])
+	case "apply":
+		cfg := ParseConfig(args[1:])
+		if cfg.Mapping == "" {
+			cfg.Mapping = defaultMappingFile
+		}
+
+		runMigration(cfg)
+	default:
+		log.Fatalf("Unknown mapping command '%s' expected generate or apply", args[0])
+	}
+}
+
+// generateMappingFile writes the mapping to the -mapping path, the
+// other flags such as -board and -project skip their questions
+func generateMappingFile(args []string) {
+	cfg := ParseConfig(args)
+	out := cfg.Mapping
+	if out == "" {
+		out = defaultMappingFile
+	}
+
+	applyTokens()
+
+	to := &TrelloOptions{Workspace: cfg.Workspace, BoardName: cfg.Board}
+	to.getCurrentUser()
+	to.getBoardsAndPromptUser()
+
+	co := &ClubhouseOptions{ProjectName: cfg.Project}
+	co.ClubhouseEntry = ch.New(clubHouseToken)
+	co.getProjectsAndPromptUser()
+
+	m := buildMapping(to, co)
+
+	b, err := yaml.Marshal(m)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(out, b, 0644); err != nil {
+		log.Fatalf("Error writing mapping file: %s", err)
+	}
+
+	fmt.Printf("*********************\n Mapping generated: %s\n*********************\n", out)
+	fmt.Println("Review and edit the file then run: mapping apply -mapping", out)
+}
+
+// buildMapping makes a best guess mapping using matching names
+func buildMapping(to *TrelloOptions, co *ClubhouseOptions) *Mapping {
+	m := Mapping{Board: to.Board.Name, Project: co.Project.Name}
+
+	lists, err := to.Board.Lists()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	workflows, err := co.ClubhouseEntry.ListWorkflow()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, l := range lists {
+		lm := ListMapping{Trello: l.Name, TrelloID: l.Id, StoryType: guessListStoryType(l.Name)}
+
+		for _, w := range workflows {
+			if w.TeamID != co.Project.TeamID {
+				continue
+			}
+
+			for _, s := range w.States {
+				if strings.EqualFold(normalizeName(s.Name), normalizeName(l.Name)) {
+					lm.ClubhouseState = s.Name
+				}
+			}
+		}
+
+		m.Lists = append(m.Lists, lm)
+	}
+
+	labels, err := getBoardLabels(to.Board.Id)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, l := range labels {
+		if n := labelName(l.Name, l.Color, to.ColorLabels); n != "" {
+			m.Labels = append(m.Labels, LabelMapping{Trello: n, Clubhouse: n})
+		}
+	}
+
+	// No import member is chosen when generating so the members are listed directly
+	chMembers := co.ListMembers()
+	for _, tm := range *to.ListMembers() {
+		mm := MemberMapping{Trello: tm.Username}
+
+		for _, u := range *chMembers {
+			if sameName(tm.FullName, u.Profile.Name) {
+				mm.Clubhouse = u.Profile.EmailAddress
+			}
+		}
+
+		m.Members = append(m.Members, mm)
+	}
+
+	return &m
+}
+
+// LoadMapping reads the human edited mapping file
+func LoadMapping(path string) *Mapping {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		fatalConfigf("Error opening mapping file: %s", err)
+	}
+
+	var m Mapping
+	if err := yaml.Unmarshal(b, &m); err != nil {
+		fatalConfigf("Error reading mapping file: %s", err)
+	}
+
+	for _, l := range m.Lists {
+		if l.StoryType != "" && !validStoryType(l.StoryType) {
+			fatalConfigf("Unknown story type '%s' for list '%s' in mapping file expected %s", l.StoryType, l.Trello, strings.Join(storyTypes, ", "))
+		}
+	}
+
+	return &m
+}
+
+// applyToConfig selects the board and project from the mapping
+// unless they were given as flags
+func (m *Mapping) applyToConfig(cfg *Config) {
+	if cfg.Board == "" {
+		cfg.Board = m.Board
+	}
+
+	if cfg.Project == "" {
+		cfg.Project = m.Project
+	}
+}
+
+// StateForList returns the clubhouse state mapped to the trello list
+func (m *Mapping) StateForList(name string, id string) string {
+	for _, l := range m.Lists {
+		if l.TrelloID == id || (l.TrelloID == "" && sameName(l.Trello, name)) {
+			return l.ClubhouseState
+		}
+	}
+
+	return ""
+}
+
+// StoryTypeForList returns the story type mapped to the trello list
+func (m *Mapping) StoryTypeForList(name string, id string) string {
+	for _, l := range m.Lists {
+		if l.TrelloID == id || (l.TrelloID == "" && sameName(l.Trello, name)) {
+			return l.StoryType
+		}
+	}
+
+	return ""
+}
+
+// guessListStoryType picks the story type for lists named after one
+// such as "Bugs" or "Chores", the others use the selected story type
+func guessListStoryType(name string) string {
+	n := strings.ToLower(name)
+	for _, t := range storyTypes {
+		if strings.Contains(n, t) {
+			return t
+		}
+	}
+
+	return ""
+}
+
+// LabelMap returns the trello to clubhouse label names
+func (m *Mapping) LabelMap() map[string]string {
+	labels := map[string]string{}
+	for _, l := range m.Labels {
+		labels[normalizeName(l.Trello)] = l.Clubhouse
+	}
+
+	return labels
+}
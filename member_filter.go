@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	trello "github.com/jnormington/go-trello"
+)
+
+// resolveOnlyMembers finds the board members given to -only-member by their
+// username (with or without the @) or member id, stopping on any not found
+func (t *TrelloOptions) resolveOnlyMembers(names []string) {
+	if len(names) == 0 {
+		return
+	}
+
+	members := *t.ListMembers()
+	t.onlyMemberIDs = map[string]bool{}
+
+	var unknown []string
+	for _, n := range names {
+		n = strings.TrimPrefix(strings.TrimSpace(n), "@")
+
+		found := false
+		for _, m := range members {
+			if strings.EqualFold(m.Username, n) || m.Id == n {
+				t.onlyMemberIDs[m.Id] = true
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			unknown = append(unknown, n)
+		}
+	}
+
+	if len(unknown) > 0 {
+		fatalConfigf("-only-member %s isn't a member of the board %s", strings.Join(unknown, ", "), t.Board.Name)
+	}
+}
+
+// onlyMembersCards keeps the cards assigned to one of the -only-member members,
+// all of them when it wasn't given
+func (t TrelloOptions) onlyMembersCards(cards []trello.Card) []trello.Card {
+	if t.onlyMemberIDs == nil {
+		return cards
+	}
+
+	var kept []trello.Card
+	for _, c := range cards {
+		for _, m := range c.IdMembers {
+			if t.onlyMemberIDs[m] {
+				kept = append(kept, c)
+				break
+			}
+		}
+	}
+
+	fmt.Printf("%d of the %d cards are assigned to the -only-member members\n", len(kept), len(cards))
+
+	return kept
+}
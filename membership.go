@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+
+	trello "github.com/jnormington/go-trello"
+)
+
+// clubhouseInvitePath is where the invitations for missing members are sent
+var clubhouseInvitePath = "/invites"
+
+// MissingMember is an active trello member without a clubhouse member,
+// their cards fall back to the import member until they are invited
+type MissingMember struct {
+	Username string `json:"username"`
+	FullName string `json:"full_name"`
+	Email    string `json:"email,omitempty"`
+	Invited  bool   `json:"invited"`
+}
+
+// activeMembers returns the ids of the members assigned to the cards or who
+// created or commented on cards in the list, without exporting the cards
+func (t *TrelloOptions) activeMembers(crds *[]trello.Card) map[string]bool {
+	active := map[string]bool{}
+	for _, c := range *crds {
+		for _, m := range c.IdMembers {
+			active[m] = true
+		}
+	}
+
+	var actions []struct {
+		IDMemberCreator string `json:"idMemberCreator"`
+	}
+
+	params := url.Values{"filter": {"createCard,commentCard"}, "fields": {"idMemberCreator"}, "limit": {"1000"}}
+	if err := trelloRequest("GET", "/lists/"+t.List.Id+"/actions", params, &actions); err != nil {
+		fmt.Println("Error: Querying the list actions for the active members ignoring...", err)
+	}
+
+	for _, a := range actions {
+		active[a.IDMemberCreator] = true
+	}
+
+	delete(active, "")
+	return active
+}
+
+// checkMembership compares the active trello members against the
+// clubhouse members and warns about the ones needing an invite
+func checkMembership(active map[string]bool, um *UserMap, invite bool) []MissingMember {
+	var seats int
+	for _, m := range *um.ClubhouseMembers {
+		if !m.Disabled && !m.Profile.Deactivated {
+			seats++
+		}
+	}
+
+	var boardActive int
+	var missing []MissingMember
+	for _, m := range *um.TrelloMembers {
+		if !active[m.Id] {
+			continue
+		}
+
+		boardActive++
+		if um.Mapping[m.Id] != "" && um.Mapping[m.Id] != um.BackupUserID {
+			continue
+		}
+
+		missing = append(missing, MissingMember{Username: m.Username, FullName: m.FullName, Email: um.Emails[m.Id]})
+	}
+
+	sort.Slice(missing, func(i, j int) bool { return missing[i].Username < missing[j].Username })
+
+	fmt.Printf("%d of %d trello board members are active on the cards, clubhouse has %d active members\n",
+		boardActive, len(*um.TrelloMembers), seats)
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	fmt.Println("****** WARNING ******")
+	fmt.Println("These trello members have no clubhouse member, their cards will use the import member:")
+	for _, m := range missing {
+		fmt.Printf("\t%s (%s) %s\n", m.Username, m.FullName, m.Email)
+	}
+
+	if invite {
+		inviteMissingMembers(missing)
+	}
+
+	report.SetMissingMembers(missing)
+	return missing
+}
+
+// inviteMissingMembers sends a clubhouse invitation to the missing members
+// with an email in the user mapping, the others are left to invite by hand
+func inviteMissingMembers(missing []MissingMember) {
+	var emails []string
+	for _, m := range missing {
+		if m.Email != "" {
+			emails = append(emails, m.Email)
+		}
+	}
+
+	if len(emails) == 0 {
+		fmt.Println("No emails in the user mapping for the missing members nobody to invite")
+		return
+	}
+
+	body := map[string]interface{}{"emails": emails}
+	if err := clubhouseRequest("POST", clubhouseInvitePath, body, nil); err != nil {
+		fmt.Println("Error: Sending the clubhouse invitations ignoring...", err)
+		return
+	}
+
+	for i := range missing {
+		missing[i].Invited = missing[i].Email != ""
+	}
+
+	fmt.Printf("Sent clubhouse invitations to %d members\n", len(emails))
+}
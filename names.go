@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// foldNames also ignores case and accents when matching names
+var foldNames bool
+
+// normalizeName puts the name in NFC so the same text typed on different
+// systems compares equal, with foldNames the accents and case are dropped too
+func normalizeName(s string) string {
+	s = norm.NFC.String(strings.TrimSpace(s))
+	if !foldNames {
+		return s
+	}
+
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		if !unicode.Is(unicode.Mn, r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+
+	return norm.NFC.String(b.String())
+}
+
+// sameName compares list, label, member, board, project or state names
+func sameName(a string, b string) bool {
+	return normalizeName(a) == normalizeName(b)
+}
+
+// duplicateNames returns the names used more than once, the pickers show
+// the ids of those so identically named boards, lists or projects can be told apart
+func duplicateNames(names []string) map[string]bool {
+	seen := map[string]int{}
+	for _, n := range names {
+		seen[normalizeName(n)]++
+	}
+
+	dups := map[string]bool{}
+	for n, c := range seen {
+		if c > 1 {
+			dups[n] = true
+		}
+	}
+
+	return dups
+}
+
+// choiceName is the name shown in a picker, with its id and where
+// it is when another has the same name
+func choiceName(name string, id string, context string, dups map[string]bool) string {
+	if !dups[normalizeName(name)] {
+		return name
+	}
+
+	if context == "" {
+		return fmt.Sprintf("%s (id %s)", name, id)
+	}
+
+	return fmt.Sprintf("%s (id %s, %s)", name, id, context)
+}
+
+// fatalAmbiguousName stops when the name given matches several,
+// listing them so the one wanted can be given by its id instead
+func fatalAmbiguousName(kind string, name string, matches []string) {
+	fatalConfigf("%s '%s' matches %d with the same name, give the id instead: %s", kind, name, len(matches), strings.Join(matches, "; "))
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	outputAuto  = ""
+	outputTable = "table"
+	outputJSON  = "json"
+	outputCSV   = "csv"
+)
+
+var outputFormat = "%-40s %-17s %s\n"
+
+// resultWriter prints a line per card as a table sized to the terminal,
+// as json lines or as csv so the results can be read by other programs
+type resultWriter struct {
+	mode  string
+	width int
+	out   io.Writer
+	csv   *csv.Writer
+
+	mu sync.Mutex
+}
+
+// resultEvent is a line of the json output
+type resultEvent struct {
+	Event   string `json:"event"`
+	CardURL string `json:"card_url"`
+	Status  string `json:"status,omitempty"`
+	Detail  string `json:"detail,omitempty"`
+	Cause   string `json:"cause,omitempty"`
+}
+
+var cardOutput = newResultWriter(outputTable, os.Stdout)
+
+func newResultWriter(mode string, out io.Writer) *resultWriter {
+	w := &resultWriter{mode: mode, out: out, csv: csv.NewWriter(out)}
+
+	if fd := int(os.Stdout.Fd()); terminal.IsTerminal(fd) {
+		w.width, _, _ = terminal.GetSize(fd)
+	}
+
+	return w
+}
+
+// setupOutput picks the output, json lines when stdout isn't a terminal unless one
+// was given. Json and csv keep stdout for the results so everything else printed
+// (progress, warnings and questions) is sent to stderr
+func setupOutput(mode string) {
+	if mode == outputAuto {
+		mode = outputTable
+		if !terminal.IsTerminal(int(os.Stdout.Fd())) {
+			mode = outputJSON
+		}
+	}
+
+	stdout := os.Stdout
+	if mode != outputTable {
+		os.Stdout = os.Stderr
+	}
+
+	cardOutput = newResultWriter(mode, stdout)
+}
+
+// Header prints the column names of the results which follow
+func (w *resultWriter) Header(cardURL string, status string, detail string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	switch w.mode {
+	case outputCSV:
+		w.csv.Write([]string{cardURL, status, detail})
+		w.csv.Flush()
+	case outputTable:
+		fmt.Fprintf(w.out, outputFormat+"\n", cardURL, status, detail)
+	}
+}
+
+// Row prints the result of a card, detail is the error or created entity
+func (w *resultWriter) Row(cardURL string, status string, detail interface{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	d := fmt.Sprint(detail)
+
+	// Failures name their cause rather than only the raw api error
+	var cause string
+	if err, ok := detail.(error); ok {
+		err = classifyError(err)
+		d, cause = err.Error(), errorCause(err)
+	}
+
+	switch w.mode {
+	case outputCSV:
+		w.csv.Write([]string{cardURL, status, d})
+		w.csv.Flush()
+	case outputJSON:
+		w.writeEvent(resultEvent{Event: eventForStatus(status), CardURL: cardURL, Status: status, Detail: d, Cause: cause})
+	default:
+		line := fmt.Sprintf(outputFormat, cardURL, status, d)
+
+		// Wrap the detail under the row rather than letting the terminal break it
+		if w.width > 0 && utf8.RuneCountInString(strings.TrimSpace(line)) > w.width {
+			line = strings.TrimRight(fmt.Sprintf(outputFormat, cardURL, status, ""), " \n") + "\n    " + d + "\n"
+		}
+
+		fmt.Fprint(w.out, line)
+	}
+}
+
+// Event prints a step of the run which isn't a card result,
+// such as a card being exported, only in the json output
+func (w *resultWriter) Event(event string, cardURL string, detail string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.mode == outputJSON {
+		w.writeEvent(resultEvent{Event: event, CardURL: cardURL, Detail: detail})
+	}
+}
+
+func (w *resultWriter) writeEvent(e resultEvent) {
+	b, _ := json.Marshal(e)
+	fmt.Fprintln(w.out, string(b))
+}
+
+// eventForStatus names the json event of a card result
+func eventForStatus(status string) string {
+	switch status {
+	case "Success":
+		return "story_created"
+	case "Failed":
+		return "error"
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(status), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	return strings.Join(words, "_")
+}
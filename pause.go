@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// hardLimitErrors are parts of the api errors for rate limits and quotas
+// which won't go away without waiting or corrective action by the user
+var hardLimitErrors = []string{
+	"insufficient_space",
+	"too_many_requests",
+	"too_many_write_operations",
+	"rate limit",
+	"returned 429",
+	"status 429",
+}
+
+var pauseOpts = []string{"Retry", "Skip", "Abort"}
+
+// uploads run concurrently so only one pause prompt is shown at a time
+var pauseMu sync.Mutex
+
+func isHardLimitError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, e := range hardLimitErrors {
+		if strings.Contains(msg, e) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// retryOnHardLimit calls the function again while it fails with a rate limit
+// or quota error and the user chooses to retry, the last error is returned
+func retryOnHardLimit(what string, call func() error) error {
+	for {
+		err := call()
+		if !isHardLimitError(err) || !promptRetryAfterLimit(what, err) {
+			return err
+		}
+	}
+}
+
+// promptRetryAfterLimit pauses the run so the limit can be fixed (freeing
+// dropbox space, waiting on a rate limit) returning true to retry
+func promptRetryAfterLimit(what string, err error) bool {
+	pauseMu.Lock()
+	defer pauseMu.Unlock()
+
+	fmt.Println("****** PAUSED ******")
+	fmt.Println("A rate limit or quota was hit for:", what)
+	fmt.Println("Error:", err)
+
+	if nonInteractive {
+		fmt.Println("Skipping this item as -non-interactive is set")
+		return false
+	}
+
+	fmt.Println("Fix the problem then choose to retry, skip this item or abort the migration")
+
+	for i, o := range pauseOpts {
+		fmt.Printf("[%d] %s\n", i, o)
+	}
+
+	switch promptUserSelectResource() {
+	case 0:
+		return true
+	case 1:
+		return false
+	default:
+		abortRun("Stopping user aborted after hitting a limit")
+	}
+
+	return false
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// tokenProbe checks a capability the import needs, the write probes send
+// a body missing required fields so an allowed request is rejected as invalid
+// and nothing is created
+type tokenProbe struct {
+	Capability string
+	Method     string
+	Path       string
+	Body       interface{}
+}
+
+// verifyTokenPermissions confirms the clubhouse token can do everything the
+// import does in the project before starting, naming each capability missing
+func (co *ClubhouseOptions) verifyTokenPermissions() {
+	probes := []tokenProbe{
+		{"read the project", "GET", fmt.Sprintf("/projects/%d", co.Project.ID), nil},
+		{"create stories in the project", "POST", "/stories", map[string]interface{}{"project_id": co.Project.ID}},
+		{"create linked files", "POST", "/linked-files", map[string]interface{}{}},
+	}
+
+	fmt.Println("Checking the Clubhouse token permissions...")
+
+	var missing []string
+	for _, p := range probes {
+		allowed, err := p.run()
+		switch {
+		case err != nil:
+			fmt.Printf("\t?  %s (couldn't check: %s)\n", p.Capability, err)
+		case allowed:
+			fmt.Printf("\tok %s\n", p.Capability)
+		default:
+			fmt.Printf("\tno %s\n", p.Capability)
+			missing = append(missing, p.Capability)
+		}
+	}
+
+	if len(missing) > 0 {
+		fatalConfigf("The Clubhouse token can't %s, use a token of a member with write access to the project", strings.Join(missing, ", "))
+	}
+
+	co.checkCommentAuthors()
+}
+
+// checkCommentAuthors falls back to posting the comments as the token member for
+// the whole run when the token can't write comments as other members, the
+// original author is named at the start of each comment instead
+func (co *ClubhouseOptions) checkCommentAuthors() {
+	p, ok := co.commentAuthorProbe()
+	if !ok {
+		fmt.Println("\t?  create comments written by other members (not checked, the project has no stories yet)")
+		return
+	}
+
+	allowed, err := p.run()
+	switch {
+	case err != nil:
+		fmt.Printf("\t?  %s (couldn't check: %s)\n", p.Capability, err)
+	case allowed:
+		fmt.Printf("\tok %s\n", p.Capability)
+	default:
+		fmt.Printf("\tno %s\n", p.Capability)
+		fmt.Println("Comments are posted by the token member starting with their original author instead")
+		co.CommentAuthorFallback = true
+	}
+}
+
+// commentAuthorProbe adds a comment written by another member to an existing story,
+// which is how the trello comments keep their author
+func (co *ClubhouseOptions) commentAuthorProbe() (tokenProbe, bool) {
+	var found struct {
+		Data []struct {
+			ID int64 `json:"id"`
+		} `json:"data"`
+	}
+
+	q := url.Values{"query": {fmt.Sprintf("project:%d", co.Project.ID)}, "page_size": {"1"}}
+	if err := clubhouseRequest("GET", "/search/stories?"+q.Encode(), nil, &found); err != nil || len(found.Data) == 0 {
+		return tokenProbe{}, false
+	}
+
+	author := co.TokenMemberID
+	for _, m := range *co.ListMembers() {
+		if m.ID != co.TokenMemberID {
+			author = m.ID
+			break
+		}
+	}
+
+	return tokenProbe{
+		Capability: "create comments written by other members",
+		Method:     "POST",
+		Path:       fmt.Sprintf("/stories/%d/comments", found.Data[0].ID),
+		Body:       map[string]interface{}{"author_id": author},
+	}, true
+}
+
+// run returns whether the token is allowed, an invalid request is
+// allowed as the permissions are checked before the body
+func (p tokenProbe) run() (bool, error) {
+	err := clubhouseRequest(p.Method, p.Path, p.Body, nil)
+	if err == nil {
+		return true, nil
+	}
+
+	var e *clubhouseAPIError
+	if !errors.As(err, &e) {
+		return false, err
+	}
+
+	switch e.Status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return true, nil
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+		return false, nil
+	}
+
+	return false, err
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/jnormington/go-trello"
+	"golang.org/x/text/unicode/norm"
+)
+
+const pickerHelp = `Select the cards to migrate by typing:
+	text       to search the card names, the letters only need to appear in order
+	1,3,5-8    to select or unselect the numbered cards
+	all        to select all the cards shown, none to unselect them
+	done       to migrate the selected cards
+Empty shows all the cards again`
+
+// pickCards lets the user select the cards of the list to migrate,
+// searching by name and toggling them by their number
+func pickCards(cards []trello.Card) []trello.Card {
+	selected := make([]bool, len(cards))
+	shown := allCardIndexes(cards)
+
+	fmt.Println(pickerHelp)
+	for {
+		printPickerCards(cards, shown, selected)
+
+		in := promptUserForText()
+		switch strings.ToLower(in) {
+		case "done":
+			var picked []trello.Card
+			for i, c := range cards {
+				if selected[i] {
+					picked = append(picked, c)
+				}
+			}
+
+			if len(picked) == 0 {
+				fmt.Println("No cards are selected, select at least one")
+				continue
+			}
+
+			fmt.Printf("%d cards selected\n", len(picked))
+			return picked
+		case "all", "none":
+			for _, i := range shown {
+				selected[i] = in == "all"
+			}
+		case "":
+			shown = allCardIndexes(cards)
+		default:
+			if nums, ok := parseCardNumbers(in, len(cards)); ok {
+				for _, i := range nums {
+					selected[i] = !selected[i]
+				}
+				continue
+			}
+
+			shown = searchCards(cards, in)
+		}
+	}
+}
+
+func allCardIndexes(cards []trello.Card) []int {
+	idx := make([]int, len(cards))
+	for i := range cards {
+		idx[i] = i
+	}
+
+	return idx
+}
+
+func printPickerCards(cards []trello.Card, shown []int, selected []bool) {
+	var count int
+	for _, s := range selected {
+		if s {
+			count++
+		}
+	}
+
+	fmt.Printf("\n%d of %d cards shown, %d selected\n", len(shown), len(cards), count)
+	for _, i := range shown {
+		mark := " "
+		if selected[i] {
+			mark = "x"
+		}
+
+		fmt.Printf("[%s] %3d %s\n", mark, i, cards[i].Name)
+	}
+}
+
+// parseCardNumbers reads comma separated numbers and ranges
+// returning false when the input isn't only numbers
+func parseCardNumbers(in string, max int) ([]int, bool) {
+	var nums []int
+	for _, p := range strings.Split(in, ",") {
+		p = strings.TrimSpace(p)
+		lo, hi := p, p
+		if r := strings.SplitN(p, "-", 2); len(r) == 2 {
+			lo, hi = strings.TrimSpace(r[0]), strings.TrimSpace(r[1])
+		}
+
+		from, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, false
+		}
+		to, err := strconv.Atoi(hi)
+		if err != nil {
+			return nil, false
+		}
+
+		for n := from; n <= to; n++ {
+			if n < 0 || n >= max {
+				fmt.Println(errOutOfRange)
+				return nil, true
+			}
+			nums = append(nums, n)
+		}
+	}
+
+	return nums, true
+}
+
+// searchCards returns the cards whose name has every word of the
+// query with its letters in order, ignoring case and accents
+func searchCards(cards []trello.Card, query string) []int {
+	var found []int
+	for i, c := range cards {
+		name := foldForSearch(c.Name)
+
+		match := true
+		for _, w := range strings.Fields(foldForSearch(query)) {
+			if !fuzzyContains(name, w) {
+				match = false
+				break
+			}
+		}
+
+		if match {
+			found = append(found, i)
+		}
+	}
+
+	return found
+}
+
+func foldForSearch(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		if !unicode.Is(unicode.Mn, r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+
+	return b.String()
+}
+
+// fuzzyContains returns true when the runes of sub appear in s in order
+func fuzzyContains(s string, sub string) bool {
+	r := []rune(sub)
+	for _, c := range s {
+		if len(r) == 0 {
+			break
+		}
+		if c == r[0] {
+			r = r[1:]
+		}
+	}
+
+	return len(r) == 0
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	trello "github.com/jnormington/go-trello"
+)
+
+// clubhouseCustomField is a custom field with its enum values
+type clubhouseCustomField struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Values []struct {
+		ID    string `json:"id"`
+		Value string `json:"value"`
+	} `json:"values"`
+}
+
+// PriorityField is the custom field the position priority is written
+// to with the value ids keyed by the priority name
+type PriorityField struct {
+	ID     string
+	Values map[string]string
+}
+
+// positionRanks returns how far down the list each card is from 0 (top)
+// towards 1 (bottom) using the trello positions
+func positionRanks(crds *[]trello.Card) map[string]float64 {
+	sorted := make([]trello.Card, len(*crds))
+	copy(sorted, *crds)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Pos < sorted[j].Pos })
+
+	ranks := map[string]float64{}
+	for i, c := range sorted {
+		ranks[c.Id] = float64(i) / float64(len(sorted))
+	}
+
+	return ranks
+}
+
+// priorityFor buckets the position of the card equally into the priorities
+// the first priority being the top of the list
+func (co *ClubhouseOptions) priorityFor(card *Card) string {
+	if len(co.Priorities) == 0 {
+		return ""
+	}
+
+	return co.Priorities[int(card.PositionRank*float64(len(co.Priorities)))]
+}
+
+// findPriorityField looks up the custom field and checks it has a value for every priority
+func (co *ClubhouseOptions) findPriorityField(name string) {
+	if name == "" {
+		return
+	}
+
+	var fields []clubhouseCustomField
+	if err := clubhouseRequest("GET", "/custom-fields", nil, &fields); err != nil {
+		log.Fatalf("Error querying the clubhouse custom fields: %s", err)
+	}
+
+	for _, f := range fields {
+		if !strings.EqualFold(f.Name, name) {
+			continue
+		}
+
+		co.PriorityField = &PriorityField{ID: f.ID, Values: map[string]string{}}
+		for _, v := range f.Values {
+			co.PriorityField.Values[v.Value] = v.ID
+		}
+
+		for _, p := range co.Priorities {
+			if co.PriorityField.Values[p] == "" {
+				fatalConfigf("Custom field '%s' has no value '%s'", name, p)
+			}
+		}
+
+		return
+	}
+
+	fatalConfigf("Custom field '%s' not found in clubhouse", name)
+}
+
+// setPriorityField writes the priority of the card to the custom field of the story
+func (co *ClubhouseOptions) setPriorityField(storyID int64, card *Card) {
+	p := co.priorityFor(card)
+	if co.PriorityField == nil || p == "" {
+		return
+	}
+
+	body := map[string]interface{}{
+		"custom_fields": []map[string]string{{"field_id": co.PriorityField.ID, "value_id": co.PriorityField.Values[p]}},
+	}
+
+	if err := clubhouseRequest("PUT", fmt.Sprintf("/stories/%d", storyID), body, nil); err != nil {
+		fmt.Println("Error: Setting the priority for card:", card.Name, "ignoring...", err)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+const defaultExportFile = "exportTtoC.jsonl"
+
+// runPromoteCommand imports the cards tried out in a sandbox project into the
+// production project with the same flags, mapping and config profile. The cards
+// saved by -save-export aren't exported again so their attachments stay uploaded
+func runPromoteCommand(args []string) {
+	cfg := ParseConfig(args)
+
+	if cfg.FromExport == "" {
+		cfg.FromExport = defaultExportFile
+	}
+	if cfg.Daemon || (cfg.Mode == modeUpdate && !cfg.Reconcile) {
+		fatalConfig("Promote can't use the story map as it holds the sandbox stories, use -mode=update with -reconcile or no update mode")
+	}
+
+	runMigration(cfg)
+}
+
+// saveExportedCards writes each card as a json line on its way to the import
+// so a later promote can import them without exporting them again
+func saveExportedCards(path string, cards <-chan Card) <-chan Card {
+	f, err := os.Create(path)
+	if err != nil {
+		fatalConfigf("Error creating the export file: %s", err)
+	}
+
+	saved := make(chan Card)
+	go func() {
+		defer close(saved)
+		defer f.Close()
+
+		enc := json.NewEncoder(f)
+		for c := range cards {
+			if err := enc.Encode(c); err != nil {
+				fmt.Println("Error: Saving the exported card:", c.Name, "ignoring...", err)
+			}
+			saved <- c
+		}
+	}()
+
+	return saved
+}
+
+// loadExportedCards reads the cards saved with -save-export by their trello id
+func loadExportedCards(path string) map[string]Card {
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		fatalConfigf("Error opening the export file: %s", err)
+	}
+	defer f.Close()
+
+	cards := map[string]Card{}
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for sc.Scan() {
+		var c Card
+		if err := json.Unmarshal(sc.Bytes(), &c); err != nil {
+			fatalConfigf("Error reading the export file: %s", err)
+		}
+		cards[c.ID] = c
+	}
+
+	if err := sc.Err(); err != nil {
+		fatalConfigf("Error reading the export file: %s", err)
+	}
+
+	fmt.Printf("Reusing %d cards exported by the sandbox run\n", len(cards))
+
+	return cards
+}
+
+// loadSandboxReport reads the report of the sandbox run before the promote
+// writes its own, its stories are deleted once the promote succeeds
+func loadSandboxReport(path string) *Report {
+	if path == "" {
+		return nil
+	}
+
+	r, err := loadReport(path)
+	if err != nil {
+		fatalConfigf("Error reading the sandbox migration report: %s", err)
+	}
+
+	return r
+}
+
+// deleteSandboxStories deletes the stories, epics and templates the sandbox run
+// created, after asking as they can't be brought back
+func deleteSandboxStories(r *Report, opts *ClubhouseOptions) {
+	if r == nil || opts.DryRun {
+		return
+	}
+
+	var created []*CardReport
+	for _, c := range r.Cards {
+		// Skipped and updated stories existed before the sandbox run
+		if c.Status == "Success" && (c.StoryID > 0 || c.EpicID > 0 || c.TemplateID != "") {
+			created = append(created, c)
+		}
+	}
+
+	if len(created) == 0 {
+		return
+	}
+
+	if !nonInteractive {
+		fmt.Printf("Delete the %d stories the sandbox run created, this can't be undone?\n", len(created))
+		for i, o := range yesNoOpts {
+			fmt.Printf("[%d] %s\n", i, o)
+		}
+
+		if promptUserSelectResource() != 0 {
+			return
+		}
+	}
+
+	for _, c := range created {
+		var err error
+		detail := ""
+
+		switch {
+		case c.EpicID > 0:
+			detail = fmt.Sprintf("Epic ID: %d", c.EpicID)
+			err = clubhouseRequest("DELETE", fmt.Sprintf("/epics/%d", c.EpicID), nil, nil)
+		case c.TemplateID != "":
+			detail = fmt.Sprintf("Template ID: %s", c.TemplateID)
+			err = clubhouseRequest("DELETE", "/entity-templates/"+c.TemplateID, nil, nil)
+		default:
+			detail = fmt.Sprintf("Story ID: %d", c.StoryID)
+			err = opts.ClubhouseEntry.DeleteStory(c.StoryID)
+		}
+
+		if err != nil {
+			cardOutput.Row(c.CardURL, "Failed", err)
+			continue
+		}
+
+		cardOutput.Row(c.CardURL, "Deleted Sandbox", detail)
+	}
+}
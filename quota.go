@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/jnormington/go-trello"
+	"github.com/tj/go-dropbox"
+)
+
+const (
+	dropboxQuotaWarn  = "warn"
+	dropboxQuotaAbort = "abort"
+	dropboxQuotaOff   = "off"
+)
+
+// checkDropboxQuota compares the size of the attachments to upload with the space
+// left in dropbox before starting, rather than failing part way through the uploads
+func checkDropboxQuota(policy string, cards []trello.Card, to *TrelloOptions) {
+	if policy == dropboxQuotaOff || !to.Attachments.Enabled {
+		return
+	}
+
+	usage, err := dropbox.New(dropboxConfig()).Users.GetSpaceUsage()
+	if err != nil {
+		fmt.Println("Error: Querying the dropbox space usage skipping the quota check...", err)
+		return
+	}
+
+	needed, err := attachmentBytes(cards, to)
+	if err != nil {
+		fmt.Println("Error: Querying the attachment sizes skipping the quota check...", err)
+		return
+	}
+
+	var free int64
+	if a := int64(usage.Allocation.Allocated); a > int64(usage.Used) {
+		free = a - int64(usage.Used)
+	}
+
+	fmt.Printf("Attachments to upload: %s, Dropbox space left: %s\n", megabytes(needed), megabytes(free))
+	if needed <= free {
+		return
+	}
+
+	msg := fmt.Sprintf("The attachments need %s more than the space left in Dropbox", megabytes(needed-free))
+	if policy == dropboxQuotaAbort {
+		fatalConfigf("%s, free some space or use -dropbox-quota=warn", msg)
+	}
+
+	fmt.Println("****** WARNING ******")
+	fmt.Println(msg, "the uploads pause for the space to be freed once it runs out")
+}
+
+// attachmentBytes sums the uploaded attachments of the cards allowed by the attachment filter
+func attachmentBytes(cards []trello.Card, to *TrelloOptions) (int64, error) {
+	ids := map[string]bool{}
+	for _, c := range cards {
+		// Saved cards were exported with their attachments already
+		if _, saved := to.SavedExport[c.Id]; !saved {
+			ids[c.Id] = true
+		}
+	}
+
+	attachments, err := listAttachments(to)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for id, as := range attachments {
+		if !ids[id] {
+			continue
+		}
+
+		for i := range as {
+			if a := &as[i]; a.IsUpload && to.Attachments.Allows(a) {
+				total += int64(a.Bytes)
+			}
+		}
+	}
+
+	return total, nil
+}
+
+// listAttachments gets the attachments of every card of the list in one request keyed by card id
+func listAttachments(to *TrelloOptions) (map[string][]trello.Attachment, error) {
+	var listCards []struct {
+		ID          string              `json:"id"`
+		Attachments []trello.Attachment `json:"attachments"`
+	}
+
+	params := url.Values{"fields": {"id"}, "attachments": {"true"}, "attachment_fields": {"bytes,isUpload,mimeType,name"}}
+	if err := trelloRequest("GET", "/lists/"+to.List.Id+"/cards", params, &listCards); err != nil {
+		return nil, err
+	}
+
+	attachments := map[string][]trello.Attachment{}
+	for _, c := range listCards {
+		attachments[c.ID] = c.Attachments
+	}
+
+	return attachments, nil
+}
+
+func megabytes(b int64) string {
+	return fmt.Sprintf("%.1f MB", float64(b)/(1024*1024))
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+
+	ch "github.com/jnormington/clubhouse-go"
+)
+
+const storyMapFile = "storyMappingTtoC.csv"
+
+var trelloShortURLRegexp = regexp.MustCompile(`https://trello\.com/c/([A-Za-z0-9]+)`)
+
+// trelloImportedCommentRegexp matches the comment with the card link added by the import
+var trelloImportedCommentRegexp = regexp.MustCompile(`Card imported from Trello: https://trello\.com/c/([A-Za-z0-9]+)`)
+
+// reconcileStories builds the card to story map from the trello card id kept as
+// the external id, then from the card link added as a comment and last from
+// the only trello card url of the description and comments. The comments are
+// fetched when the story list doesn't include them
+func reconcileStories(opts *ClubhouseOptions, stories []ch.Story) map[string]int64 {
+	m := map[string]int64{}
+
+	for _, st := range stories {
+		u := cardShortURLForExternalID(st.ExternalID)
+		if u == "" && len(st.Comments) == 0 {
+			full, err := opts.ClubhouseEntry.GetStory(st.ID)
+			if err != nil {
+				fmt.Println("Error: Querying comments for story:", st.ID, "ignoring...", err)
+				continue
+			}
+			st = full
+		}
+		if u == "" {
+			u = findTrelloShortURL(st)
+		}
+
+		if u != "" {
+			m[u] = st.ID
+		}
+	}
+
+	fmt.Printf("Reconciled %d of %d existing stories with their trello cards\n", len(m), len(stories))
+	return m
+}
+
+// cardShortURLForExternalID looks up the card of a trello card id external id
+func cardShortURLForExternalID(id string) string {
+	if !trelloCardIDRegexp.MatchString(id) {
+		return ""
+	}
+
+	var card struct {
+		ShortURL string `json:"shortUrl"`
+	}
+
+	if err := trelloRequest("GET", "/cards/"+id, url.Values{"fields": {"shortUrl"}}, &card); err != nil {
+		fmt.Println("Error: Querying the trello card:", id, "ignoring...", err)
+		return ""
+	}
+
+	return card.ShortURL
+}
+
+// findTrelloShortURL returns the card link added as a comment by the import,
+// otherwise the trello card url when the story has only one. A story linking
+// to several cards, e.g. with expanded card links, isn't guessed
+func findTrelloShortURL(st ch.Story) string {
+	for _, c := range st.Comments {
+		if m := trelloImportedCommentRegexp.FindStringSubmatch(c.Text); m != nil {
+			return "https://trello.com/c/" + m[1]
+		}
+	}
+
+	texts := []string{st.Description}
+	for _, c := range st.Comments {
+		texts = append(texts, c.Text)
+	}
+
+	found := map[string]bool{}
+	for _, t := range texts {
+		for _, m := range trelloShortURLRegexp.FindAllStringSubmatch(t, -1) {
+			found[m[1]] = true
+		}
+	}
+
+	if len(found) != 1 {
+		return ""
+	}
+
+	for id := range found {
+		return "https://trello.com/c/" + id
+	}
+
+	return ""
+}
+
+// writeStoryMap saves the card to story map as a csv which can be
+// reviewed and used by later runs, with the list each card was in
+func writeStoryMap(path string, m map[string]int64, lists map[string]string) {
+	var urls []string
+	for u := range m {
+		urls = append(urls, u)
+	}
+	sort.Strings(urls)
+
+	rows := [][]string{{"TrelloCard", "StoryID", "TrelloList"}}
+	for _, u := range urls {
+		rows = append(rows, []string{u, strconv.FormatInt(m[u], 10), lists[u]})
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		fmt.Println("Error: Creating the story mapping file ignoring...", err)
+		return
+	}
+	defer f.Close()
+
+	if err := csv.NewWriter(f).WriteAll(rows); err != nil {
+		fmt.Println("Error: Writing the story mapping file ignoring...", err)
+		return
+	}
+
+	fmt.Printf("*********************\n Story mapping: %s\n*********************\n", path)
+}
+
+// loadStoryMap reads a card to story map and the card lists written by
+// writeStoryMap, maps written before the lists were kept have no lists
+func loadStoryMap(path string) (map[string]int64, map[string]string) {
+	f, err := os.Open(path)
+	if err != nil {
+		fatalConfigf("Error opening story mapping file: %s", err)
+	}
+	defer f.Close()
+
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		fatalConfigf("Error reading story mapping file: %s", err)
+	}
+
+	m := map[string]int64{}
+	lists := map[string]string{}
+	for i, r := range rows {
+		if i == 0 || len(r) < 2 {
+			// Its the header row
+			continue
+		}
+
+		id, err := strconv.ParseInt(r[1], 10, 64)
+		if err != nil {
+			fatalConfigf("Invalid story id '%s' for %s in story mapping file", r[1], r[0])
+		}
+		m[r[0]] = id
+		if len(r) > 2 && r[2] != "" {
+			lists[r[0]] = r[2]
+		}
+	}
+
+	return m, lists
+}
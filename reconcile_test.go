@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+
+	ch "github.com/jnormington/clubhouse-go"
+)
+
+func TestFindTrelloShortURL(t *testing.T) {
+	tests := []struct {
+		desc string
+		want string
+	}{
+		{"Imported from https://trello.com/c/abc123", "https://trello.com/c/abc123"},
+		{"See https://trello.com/c/abc123 and again https://trello.com/c/abc123/1-login", "https://trello.com/c/abc123"},
+		// A card linking to another card can't tell which one it was
+		{"Blocked by https://trello.com/c/abc123 see https://trello.com/c/def456", ""},
+		{"No card link", ""},
+	}
+
+	for _, tt := range tests {
+		if got := findTrelloShortURL(ch.Story{Description: tt.desc}); got != tt.want {
+			t.Errorf("findTrelloShortURL(%q) = '%s' expected '%s'", tt.desc, got, tt.want)
+		}
+	}
+}
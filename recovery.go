@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// promptRecovery explains why nothing was found when choosing a board, list or project,
+// usually a token or permission problem, and offers to fix it without restarting.
+// Quitting or running non interactively stops with the explanation
+func promptRecovery(problem string, causes []string, fix string) {
+	if nonInteractive {
+		fatalConfigf("%s, likely because %s", problem, strings.Join(causes, " or "))
+	}
+
+	fmt.Printf("****** %s ******\n", strings.ToUpper(problem))
+	fmt.Println("This is likely because")
+	for _, c := range causes {
+		fmt.Println("\t-", c)
+	}
+
+	fmt.Println("What would you like to do ?")
+	fmt.Printf("[0] %s\n[1] Quit\n", fix)
+
+	if promptUserSelectResource() != 0 {
+		abortRun("Stopping user quit as " + strings.ToLower(problem[:1]) + problem[1:])
+	}
+}
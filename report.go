@@ -0,0 +1,414 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultReportFile = "migrationReportTtoC.json"
+
+// Cards finished during the run are spilled to this file next to the
+// report, one json line each, so the report doesn't grow in memory
+const reportSpillSuffix = ".partial"
+
+// Report collects what happened to each card during the migration
+// it is written as json at the end of the run
+type Report struct {
+	Cards          []*CardReport       `json:"cards"`
+	MissingMembers []MissingMember     `json:"missing_members,omitempty"`
+	Automations    []AutomationReport  `json:"automations,omitempty"`
+	DataLoss       map[string][]string `json:"data_loss,omitempty"`
+	FailureCauses  map[string]int      `json:"failure_causes,omitempty"`
+
+	mu    sync.Mutex
+	index map[string]*CardReport
+	spill *os.File
+}
+
+// CardReport is the outcome of migrating a single card
+type CardReport struct {
+	CardURL     string             `json:"card_url"`
+	CardName    string             `json:"card_name"`
+	StoryID     int64              `json:"story_id,omitempty"`
+	EpicID      int64              `json:"epic_id,omitempty"`
+	TemplateID  string             `json:"template_id,omitempty"`
+	Status      string             `json:"status"`
+	Error       string             `json:"error,omitempty"`
+	Cause       string             `json:"cause,omitempty"`
+	Attachments []AttachmentReport `json:"attachments,omitempty"`
+	Expected    *ExpectedStory     `json:"expected,omitempty"`
+	DateErrors  []DateErrorReport  `json:"date_errors,omitempty"`
+	Diff        []string           `json:"diff,omitempty"`
+	FailedLinks []string           `json:"failed_links,omitempty"`
+	Sensitive   []string           `json:"sensitive_data,omitempty"`
+
+	TimeInListsHours map[string]float64 `json:"time_in_lists_hours,omitempty"`
+}
+
+// AttachmentReport records the upload and verification of an attachment
+type AttachmentReport struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256"`
+	Verified bool   `json:"verified"`
+	Attempts int    `json:"attempts"`
+	Error    string `json:"error,omitempty"`
+}
+
+// DateErrorReport records a trello date which couldn't be parsed
+type DateErrorReport struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+}
+
+var report = newReport()
+
+func newReport() *Report {
+	return &Report{index: map[string]*CardReport{}}
+}
+
+// card returns the report for the card creating it when needed,
+// the caller must hold the lock
+func (r *Report) card(url string, name string) *CardReport {
+	c, ok := r.index[url]
+	if !ok {
+		c = &CardReport{CardURL: url, CardName: name}
+		r.index[url] = c
+		r.Cards = append(r.Cards, c)
+	}
+
+	return c
+}
+
+// AddAttachment records an attachment uploaded for the card
+func (r *Report) AddAttachment(url string, name string, a AttachmentReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := r.card(url, name)
+	c.Attachments = append(c.Attachments, a)
+
+	if a.Error != "" {
+		r.addDataLoss(lossAttachments, url, name, fmt.Sprintf("%s %s", a.Name, a.Error))
+	}
+}
+
+// AddFailedLink records a linked file which couldn't be added to the story
+func (r *Report) AddFailedLink(url string, name string, link string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := r.card(url, name)
+	c.FailedLinks = append(c.FailedLinks, link)
+	r.addDataLoss(lossLinks, url, name, link)
+}
+
+// AddDateError records a date of the card which couldn't be parsed
+func (r *Report) AddDateError(url string, name string, e DateErrorReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := r.card(url, name)
+	c.DateErrors = append(c.DateErrors, e)
+	r.addDataLoss(lossDates, url, name, fmt.Sprintf("%s date %s", e.Field, e.Value))
+}
+
+// SetSensitiveData records the possible secrets and personal data found in the card,
+// the card is blocked from the import with -scan-secrets=block
+func (r *Report) SetSensitiveData(url string, name string, found []string, blocked bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := r.card(url, name)
+	c.Sensitive = found
+	if blocked {
+		c.Status = "Blocked"
+	}
+
+	r.addDataLoss(lossSensitive, url, name, strings.Join(found, ", "))
+}
+
+// SetMissingMembers records the trello members without a clubhouse member
+func (r *Report) SetMissingMembers(m []MissingMember) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.MissingMembers = m
+
+	for _, mm := range m {
+		r.addDataLoss(lossMembers, "", "", fmt.Sprintf("%s (%s)", mm.FullName, mm.Username))
+	}
+}
+
+// SetAutomations records the board automations which aren't migrated
+func (r *Report) SetAutomations(a []AutomationReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Automations = a
+}
+
+// SetResult records the import result of the card
+func (r *Report) SetResult(url string, name string, storyID int64, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := r.card(url, name)
+	c.StoryID = storyID
+	c.Status = "Success"
+
+	if err != nil {
+		err = classifyError(err)
+		c.Status = "Failed"
+		c.Error = err.Error()
+		c.Cause = errorCause(err)
+		r.countFailure(c.Cause)
+		r.addDataLoss(lossFailedCards, url, name, c.Error)
+	}
+}
+
+func (r *Report) countFailure(cause string) {
+	if r.FailureCauses == nil {
+		r.FailureCauses = map[string]int{}
+	}
+	r.FailureCauses[cause]++
+}
+
+// SetSkipped records a card skipped as its story already exists
+func (r *Report) SetSkipped(url string, name string, storyID int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := r.card(url, name)
+	c.StoryID = storyID
+	c.Status = "Skipped"
+}
+
+// SetUpdated records the result of updating the existing story of the card
+func (r *Report) SetUpdated(url string, name string, storyID int64, err error) {
+	r.SetResult(url, name, storyID, err)
+
+	if err == nil {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.card(url, name).Status = "Updated"
+	}
+}
+
+// SetDryRun records what the import would do for the card
+func (r *Report) SetDryRun(url string, name string, storyID int64, status string, diff []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := r.card(url, name)
+	c.StoryID = storyID
+	c.Status = status
+	c.Diff = append(c.Diff, diff...)
+}
+
+// SetEpicResult records the import result of a card converted to an epic
+func (r *Report) SetEpicResult(url string, name string, epicID int64, err error) {
+	r.SetResult(url, name, 0, err)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.card(url, name).EpicID = epicID
+}
+
+// SetTemplateResult records the import result of a card imported as a story template
+func (r *Report) SetTemplateResult(url string, name string, templateID string, err error) {
+	r.SetResult(url, name, 0, err)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.card(url, name).TemplateID = templateID
+}
+
+// SetTimeInLists records how long the card spent in each list
+func (r *Report) SetTimeInLists(url string, name string, durations map[string]time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	hours := map[string]float64{}
+	for l, d := range durations {
+		hours[l] = math.Round(d.Hours()*100) / 100
+	}
+
+	r.card(url, name).TimeInListsHours = hours
+}
+
+// SetExpected records what was sent to clubhouse for the card
+func (r *Report) SetExpected(url string, name string, e *ExpectedStory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.card(url, name).Expected = e
+}
+
+// Spill starts writing the finished cards to the spill file of the report path
+func (r *Report) Spill(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, err := os.Create(path + reportSpillSuffix)
+	if err != nil {
+		fmt.Println("Error: Creating the migration report spill file keeping it in memory...", err)
+		return
+	}
+
+	r.spill = f
+}
+
+// Done spills the finished card to disk and releases it from memory,
+// without a spill file the card is kept until the report is written
+func (r *Report) Done(url string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.index[url]
+	if !ok || r.spill == nil {
+		return
+	}
+
+	b, err := json.Marshal(c)
+	if err != nil {
+		fmt.Println("Error: Building the migration report for:", c.CardName, "keeping it in memory...", err)
+		return
+	}
+
+	if _, err := r.spill.Write(append(b, '\n')); err != nil {
+		fmt.Println("Error: Writing the migration report for:", c.CardName, "keeping it in memory...", err)
+		return
+	}
+
+	delete(r.index, url)
+	for i := range r.Cards {
+		if r.Cards[i] == c {
+			r.Cards = append(r.Cards[:i], r.Cards[i+1:]...)
+			break
+		}
+	}
+}
+
+// Write saves the report as json to the path, the spilled cards are
+// copied line by line from the spill file before the cards in memory
+func (r *Report) Write(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.write(path); err != nil {
+		fmt.Println("Error: Writing the migration report ignoring...", err)
+		return
+	}
+
+	if r.spill != nil {
+		r.spill.Close()
+		os.Remove(r.spill.Name())
+		r.spill = nil
+	}
+
+	fmt.Printf("*********************\n Migration report: %s\n*********************\n", path)
+}
+
+func (r *Report) write(path string) error {
+	if r.spill == nil {
+		b, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		return ioutil.WriteFile(path, b, 0644)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	w.WriteString("{\n  \"cards\": [")
+
+	var n int
+	next := func(b []byte) {
+		if n > 0 {
+			w.WriteString(",")
+		}
+		w.WriteString("\n    ")
+		w.Write(b)
+		n++
+	}
+
+	sp, err := os.Open(r.spill.Name())
+	if err != nil {
+		return err
+	}
+	defer sp.Close()
+
+	sc := bufio.NewScanner(sp)
+	sc.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for sc.Scan() {
+		next(sc.Bytes())
+	}
+
+	if err := sc.Err(); err != nil {
+		return err
+	}
+
+	for _, c := range r.Cards {
+		b, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+		next(b)
+	}
+
+	w.WriteString("\n  ]")
+
+	if len(r.MissingMembers) > 0 {
+		if err := writeReportField(w, "missing_members", r.MissingMembers); err != nil {
+			return err
+		}
+	}
+
+	if len(r.Automations) > 0 {
+		if err := writeReportField(w, "automations", r.Automations); err != nil {
+			return err
+		}
+	}
+
+	if len(r.DataLoss) > 0 {
+		if err := writeReportField(w, "data_loss", r.DataLoss); err != nil {
+			return err
+		}
+	}
+
+	if len(r.FailureCauses) > 0 {
+		if err := writeReportField(w, "failure_causes", r.FailureCauses); err != nil {
+			return err
+		}
+	}
+
+	w.WriteString("\n}\n")
+
+	return w.Flush()
+}
+
+func writeReportField(w *bufio.Writer, name string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, ",\n  %q: ", name)
+	_, err = w.Write(b)
+	return err
+}
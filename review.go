@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	ch "github.com/jnormington/clubhouse-go"
+)
+
+const (
+	reviewAll     = "all"
+	reviewInvalid = "invalid"
+
+	maxStoryNameLength     = 512
+	reviewDescriptionRunes = 500
+)
+
+var reviewOpts = []string{"Approve", "Edit name", "Edit story type", "Edit workflow state", "Skip"}
+
+// validateStory returns the problems with the story which has been built,
+// cards with problems are reviewed with -review=invalid
+func validateStory(cs *ch.CreateStory) []string {
+	var problems []string
+
+	if strings.TrimSpace(cs.Name) == "" {
+		problems = append(problems, "the name is empty")
+	}
+
+	if utf8.RuneCountInString(cs.Name) > maxStoryNameLength {
+		problems = append(problems, fmt.Sprintf("the name is over %d characters", maxStoryNameLength))
+	}
+
+	if strings.HasSuffix(cs.Description, truncatedNotice) || strings.HasSuffix(cs.Description, continuedNotice) {
+		problems = append(problems, "the description was too long")
+	}
+
+	return problems
+}
+
+// reviewStory shows the story before it is created so it can be approved,
+// edited or skipped, returns false when the card is skipped
+func reviewStory(card *Card, cs *ch.CreateStory, opts *ClubhouseOptions) bool {
+	problems := validateStory(cs)
+	if opts.Review == reviewInvalid && len(problems) == 0 {
+		return true
+	}
+
+	for {
+		printStoryForReview(card, cs, opts, problems)
+
+		for i, o := range reviewOpts {
+			fmt.Printf("[%d] %s\n", i, o)
+		}
+
+		switch promptUserSelectResource() {
+		case 0:
+			return true
+		case 1:
+			fmt.Println("Please enter the story name:")
+			if n := promptUserForText(); n != "" {
+				cs.Name = n
+			}
+		case 2:
+			for i, t := range storyTypes {
+				fmt.Printf("[%d] %s\n", i, t)
+			}
+
+			i := promptUserSelectResource()
+			if i >= len(storyTypes) {
+				log.Fatal(errOutOfRange)
+			}
+			cs.StoryType = storyTypes[i]
+		case 3:
+			for i, s := range opts.States {
+				fmt.Printf("[%d] %s\n", i, s.Name)
+			}
+
+			i := promptUserSelectResource()
+			if i >= len(opts.States) {
+				log.Fatal(errOutOfRange)
+			}
+			cs.WorkflowStateID = opts.States[i].ID
+		case 4:
+			return false
+		default:
+			log.Fatal(errOutOfRange)
+		}
+
+		problems = validateStory(cs)
+	}
+}
+
+func printStoryForReview(card *Card, cs *ch.CreateStory, opts *ClubhouseOptions, problems []string) {
+	state := fmt.Sprint(cs.WorkflowStateID)
+	for _, s := range opts.States {
+		if s.ID == cs.WorkflowStateID {
+			state = s.Name
+		}
+	}
+
+	var labels []string
+	for _, l := range cs.Labels {
+		labels = append(labels, l.Name)
+	}
+
+	desc := cs.Description
+	if utf8.RuneCountInString(desc) > reviewDescriptionRunes {
+		desc = desc[:runeOffset(desc, reviewDescriptionRunes)] + "..."
+	}
+
+	fmt.Printf("\n****** Review %s ******\n", card.ShortURL)
+	fmt.Printf("Name: %s\nType: %s\nState: %s\nLabels: %s\n", cs.Name, cs.StoryType, state, strings.Join(labels, ", "))
+	fmt.Printf("Owners: %d Tasks: %d Comments: %d Files: %d\n", len(cs.OwnerIds), len(cs.Tasks), len(cs.Comments), reviewFiles(card, opts))
+	fmt.Printf("Description:\n%s\n\n", desc)
+
+	for _, p := range problems {
+		fmt.Println("Problem:", p)
+	}
+}
+
+// reviewFiles counts the linked files the story gets, they are only created once approved
+func reviewFiles(card *Card, opts *ClubhouseOptions) int {
+	n := len(card.Attachments)
+	if opts.URLAttachments == urlAttachmentsLinkedFile {
+		n += len(card.Links)
+	}
+
+	return n
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+
+	trello "github.com/jnormington/go-trello"
+)
+
+// sampleCards keeps every nth card then at most limit of them, so a pilot run
+// sees cards from the whole list rather than only its top, zero keeps them all
+func sampleCards(cards []trello.Card, every int, limit int) []trello.Card {
+	if every <= 1 && limit == 0 {
+		return cards
+	}
+
+	var kept []trello.Card
+	for i, c := range cards {
+		if every > 1 && i%every != 0 {
+			continue
+		}
+		if limit > 0 && len(kept) == limit {
+			break
+		}
+
+		kept = append(kept, c)
+	}
+
+	fmt.Printf("Migrating a sample of %d of the %d cards\n", len(kept), len(cards))
+
+	return kept
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	scanSecretsOff    = "off"
+	scanSecretsReport = "report"
+	scanSecretsBlock  = "block"
+)
+
+// sensitivePattern is a kind of secret or personal data recognized by its shape,
+// check rules out matches of the shape which aren't it
+type sensitivePattern struct {
+	Kind  string
+	re    *regexp.Regexp
+	check func(string) bool
+}
+
+var sensitivePatterns = []sensitivePattern{
+	{Kind: "private key", re: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+	{Kind: "aws access key", re: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{Kind: "github token", re: regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36}\b`)},
+	{Kind: "slack token", re: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{Kind: "password", re: regexp.MustCompile(`(?i)\b(?:password|passwd|pwd|secret|api[_-]?key|access[_-]?token)\s*[:=]\s*\S{4,}`)},
+	{Kind: "credit card number", re: regexp.MustCompile(`\b(?:\d[ -]?){13,19}\b`), check: luhnValid},
+	{Kind: "social security number", re: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)},
+	{Kind: "email address", re: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)},
+}
+
+// scanCard returns what possible secrets and personal data are where in the card,
+// e.g. "aws access key in comment 2", the values aren't returned so the
+// report doesn't spread them
+func scanCard(card *Card) []string {
+	texts := map[string]string{"name": card.Name, "description": card.Desc}
+	for i, c := range card.Comments {
+		texts[fmt.Sprintf("comment %d", i+1)] = c.Text
+	}
+	for name := range card.Attachments {
+		texts["attachment "+name] = name
+	}
+
+	var found []string
+	for where, text := range texts {
+		for _, p := range sensitivePatterns {
+			if p.matches(text) {
+				found = append(found, p.Kind+" in "+where)
+			}
+		}
+	}
+
+	sort.Strings(found)
+	return found
+}
+
+func (p sensitivePattern) matches(text string) bool {
+	for _, m := range p.re.FindAllString(text, -1) {
+		if p.check == nil || p.check(m) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// luhnValid returns true when the digits pass the card number checksum
+func luhnValid(s string) bool {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(s)
+	if len(digits) < 13 {
+		return false
+	}
+
+	sum := 0
+	for i := range digits {
+		d := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+
+	return sum%10 == 0
+}
+
+// scanCardForSecrets flags the possible secrets of the card in the report,
+// returning true when the card is blocked from the import
+func scanCardForSecrets(policy string, card *Card) bool {
+	if policy == scanSecretsOff || policy == "" {
+		return false
+	}
+
+	found := scanCard(card)
+	if len(found) == 0 {
+		return false
+	}
+
+	blocked := policy == scanSecretsBlock
+	report.SetSensitiveData(card.ShortURL, card.Name, found, blocked)
+
+	if blocked {
+		cardOutput.Row(card.ShortURL, "Blocked", "possible "+strings.Join(found, ", "))
+	}
+
+	return blocked
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+var (
+	stopOnce sync.Once
+	stopped  = make(chan struct{})
+)
+
+// watchStopSignals stops the run gracefully on SIGINT or SIGTERM (a container
+// being evicted), the card being imported is finished and the report and story
+// map are written before exiting. A second signal exits straight away
+func watchStopSignals() {
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		s := <-sigs
+		fmt.Fprintln(os.Stderr, "Received", s, "finishing the current card then stopping, send it again to stop now")
+		stopOnce.Do(func() { close(stopped) })
+
+		<-sigs
+		os.Exit(exitAborted)
+	}()
+}
+
+// stopRequested returns true once the run was asked to stop
+func stopRequested() bool {
+	select {
+	case <-stopped:
+		return true
+	default:
+		return false
+	}
+}
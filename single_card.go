@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+
+	trello "github.com/jnormington/go-trello"
+)
+
+// useCardBoardAndList migrates only the -card, its board and list are used
+// in place of the -board and -list so they aren't asked
+func (t *TrelloOptions) useCardBoardAndList(cardURL string) {
+	if cardURL == "" {
+		return
+	}
+
+	link := cardURL
+	if m := trelloShortURLRegexp.FindStringSubmatch(cardURL); m != nil {
+		link = m[1]
+	}
+
+	var card struct {
+		ID      string `json:"id"`
+		Name    string `json:"name"`
+		IDBoard string `json:"idBoard"`
+		IDList  string `json:"idList"`
+		Closed  bool   `json:"closed"`
+	}
+
+	params := url.Values{"fields": {"name,idBoard,idList,closed"}}
+	if err := trelloRequest("GET", "/cards/"+link, params, &card); err != nil {
+		fatalConfigf("Error finding the card %s: %s", cardURL, err)
+	}
+
+	if card.Closed {
+		fatalConfigf("The card %s is archived, restore it in Trello to migrate it", cardURL)
+	}
+
+	t.Workspace, t.BoardName, t.ListName = "", card.IDBoard, card.IDList
+	t.onlyCardID = card.ID
+
+	fmt.Println("Migrating only the card:", card.Name)
+}
+
+// onlyCardOfList keeps the -card from the cards of its list
+func (t TrelloOptions) onlyCardOfList(cards []trello.Card) []trello.Card {
+	if t.onlyCardID == "" {
+		return cards
+	}
+
+	for _, c := range cards {
+		if c.Id == t.onlyCardID {
+			return []trello.Card{c}
+		}
+	}
+
+	return nil
+}
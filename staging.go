@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	trello "github.com/jnormington/go-trello"
+	"github.com/tj/go-dropbox"
+)
+
+// stagedAttachment is an attachment downloaded to a temporary file
+// with the hashes needed to verify the upload
+type stagedAttachment struct {
+	Path        string
+	Size        int64
+	SHA256      string
+	ContentHash string
+}
+
+// invalidDownloadError is a download which isn't the attachment, such as an
+// empty file or the html error page of an expired or unauthorized link
+type invalidDownloadError struct {
+	reason string
+}
+
+func (e *invalidDownloadError) Error() string {
+	return e.reason
+}
+
+func stageTrelloAttachment(f *trello.Attachment) (*stagedAttachment, error) {
+	resp := downloadTrelloAttachment(f)
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return nil, &invalidDownloadError{fmt.Sprintf("download returned %d", resp.StatusCode)}
+	}
+
+	tmp, err := ioutil.TempFile("", "trello-attachment-")
+	if err != nil {
+		return nil, err
+	}
+
+	sh := sha256.New()
+	ch := newDropboxContentHash()
+	head := &headWriter{max: 512}
+
+	n, err := io.Copy(io.MultiWriter(tmp, sh, ch, head), resp.Body)
+
+	// Windows can't remove or reopen the file while it is still open
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+
+	if err = validateDownload(f, n, resp.Header.Get("Content-Type"), head.b); err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+
+	return &stagedAttachment{
+		Path:        tmp.Name(),
+		Size:        n,
+		SHA256:      hex.EncodeToString(sh.Sum(nil)),
+		ContentHash: ch.Hex(),
+	}, nil
+}
+
+// Matches checks the uploaded file against the downloaded content,
+// the size is used alone when dropbox doesn't return a content hash
+func (s *stagedAttachment) Matches(o *dropbox.UploadOutput) bool {
+	if o.Size != uint64(s.Size) {
+		return false
+	}
+
+	return o.ContentHash == "" || o.ContentHash == s.ContentHash
+}
+
+func (s *stagedAttachment) Remove() {
+	os.Remove(s.Path)
+}
+
+// validateDownload rejects empty downloads and html pages
+// downloaded in place of an attachment which isn't html
+func validateDownload(f *trello.Attachment, size int64, contentType string, head []byte) error {
+	if size == 0 {
+		return &invalidDownloadError{"downloaded file is empty"}
+	}
+
+	if strings.Contains(strings.ToLower(f.MimeType), "html") {
+		return nil
+	}
+
+	if strings.HasPrefix(strings.ToLower(contentType), "text/html") || strings.HasPrefix(http.DetectContentType(head), "text/html") {
+		return &invalidDownloadError{fmt.Sprintf("downloaded an html page instead of the %s file", attachmentMimeType(f))}
+	}
+
+	return nil
+}
+
+// headWriter keeps the first max bytes written to it for content sniffing
+type headWriter struct {
+	max int
+	b   []byte
+}
+
+func (w *headWriter) Write(p []byte) (int, error) {
+	if n := w.max - len(w.b); n > 0 {
+		if len(p) < n {
+			n = len(p)
+		}
+		w.b = append(w.b, p[:n]...)
+	}
+
+	return len(p), nil
+}
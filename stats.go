@@ -0,0 +1,171 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// boardStats is what the stats command counts to scope a migration
+type boardStats struct {
+	Cards           int
+	CardsPerList    map[string]int
+	CardsPerLabel   map[string]int
+	ActionsByMember map[string]int
+	Comments        int
+	Attachments     int
+	UploadedBytes   int64
+}
+
+// runStatsCommand prints the size of a board before migrating it so it
+// can be scoped and split, it only reads from trello
+func runStatsCommand(args []string) {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	workspace := fs.String("workspace", "", "name or id of the trello workspace to list boards from")
+	board := fs.String("board", "", "name or id of the trello board, asked when not given")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: trello-to-clubhouse stats [-workspace name] [-board name]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	applyTokens()
+
+	t := TrelloOptions{Workspace: *workspace, BoardName: *board}
+	t.getCurrentUser()
+	t.getBoardsAndPromptUser()
+
+	fmt.Println("Please wait while we count the board... This might take a few minutes.")
+	printBoardStats(t.Board.Name, collectBoardStats(t.Board.Id))
+}
+
+func collectBoardStats(boardID string) *boardStats {
+	s := &boardStats{
+		CardsPerList:    map[string]int{},
+		CardsPerLabel:   map[string]int{},
+		ActionsByMember: map[string]int{},
+	}
+
+	var lists []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := trelloRequest("GET", "/boards/"+boardID+"/lists", url.Values{"fields": {"name"}}, &lists); err != nil {
+		log.Fatalf("Error querying the board lists: %s", err)
+	}
+
+	listNames := map[string]string{}
+	for _, l := range lists {
+		listNames[l.ID] = l.Name
+	}
+
+	labels, err := getBoardLabels(boardID)
+	if err != nil {
+		log.Fatalf("Error querying the board labels: %s", err)
+	}
+
+	labelNames := map[string]string{}
+	for _, l := range labels {
+		labelNames[l.ID] = labelName(l.Name, l.Color, colorLabelsColor)
+	}
+
+	var cards []struct {
+		IDList   string   `json:"idList"`
+		IDLabels []string `json:"idLabels"`
+		Badges   struct {
+			Comments int `json:"comments"`
+		} `json:"badges"`
+		Attachments []struct {
+			Bytes    int64 `json:"bytes"`
+			IsUpload bool  `json:"isUpload"`
+		} `json:"attachments"`
+	}
+
+	params := url.Values{"fields": {"idList,idLabels,badges"}, "attachments": {"true"}, "attachment_fields": {"bytes,isUpload"}}
+	if err := trelloRequest("GET", "/boards/"+boardID+"/cards", params, &cards); err != nil {
+		log.Fatalf("Error querying the board cards: %s", err)
+	}
+
+	for _, c := range cards {
+		s.Cards++
+		s.CardsPerList[listNames[c.IDList]]++
+		s.Comments += c.Badges.Comments
+
+		for _, l := range c.IDLabels {
+			s.CardsPerLabel[labelNames[l]]++
+		}
+
+		for _, a := range c.Attachments {
+			s.Attachments++
+			if a.IsUpload {
+				s.UploadedBytes += a.Bytes
+			}
+		}
+	}
+
+	countMemberActions(boardID, s.ActionsByMember)
+	return s
+}
+
+// countMemberActions pages through the board history counting the actions of each member
+func countMemberActions(boardID string, counts map[string]int) {
+	params := url.Values{"fields": {"id"}, "memberCreator_fields": {"fullName,username"}, "limit": {strconv.Itoa(actionsPageSize)}}
+	for {
+		var page []struct {
+			ID            string `json:"id"`
+			MemberCreator struct {
+				FullName string `json:"fullName"`
+				Username string `json:"username"`
+			} `json:"memberCreator"`
+		}
+
+		if err := trelloRequest("GET", "/boards/"+boardID+"/actions", params, &page); err != nil {
+			fmt.Println("Error: Querying the board actions, member activity is incomplete...", err)
+			return
+		}
+
+		for _, a := range page {
+			counts[fmt.Sprintf("%s (%s)", a.MemberCreator.FullName, a.MemberCreator.Username)]++
+		}
+
+		if len(page) < actionsPageSize {
+			return
+		}
+
+		params.Set("before", page[len(page)-1].ID)
+	}
+}
+
+func printBoardStats(name string, s *boardStats) {
+	fmt.Printf("\nBoard: %s\n", name)
+	fmt.Printf("Open cards: %d\nComments: %d\nAttachments: %d (%.1f MB uploaded to trello)\n",
+		s.Cards, s.Comments, s.Attachments, float64(s.UploadedBytes)/(1024*1024))
+
+	printCounts("Cards per list", s.CardsPerList)
+	printCounts("Cards per label", s.CardsPerLabel)
+	printCounts("Actions per member", s.ActionsByMember)
+}
+
+// printCounts prints the counts largest first
+func printCounts(title string, counts map[string]int) {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	fmt.Printf("\n%s\n", title)
+	for _, k := range keys {
+		fmt.Printf("\t%-40s %d\n", k, counts[k])
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	ch "github.com/jnormington/clubhouse-go"
+)
+
+var update = flag.Bool("update", false, "regenerate the golden stories in testdata/stories")
+
+// storyFixtureOptions changes the options for the fixture of the same name
+var storyFixtureOptions = map[string]func(*ClubhouseOptions){
+	"digest_comments": func(co *ClubhouseOptions) {
+		co.CommentsMode = commentsDigest
+		co.MetadataFooter = true
+	},
+	"attachments_due_complete": func(co *ClubhouseOptions) {
+		co.URLAttachments = urlAttachmentsDescription
+		co.InlineImages = true
+		co.DueComplete = dueCompleteLabel
+		co.LabelNamespace = "trello:"
+	},
+}
+
+// TestStoryFromCard builds the story of each card in testdata/cards and compares
+// it with its golden story, run with -update after changing the mapping
+func TestStoryFromCard(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "cards", "*.json"))
+	if err != nil || len(fixtures) == 0 {
+		t.Fatalf("no card fixtures found: %v", err)
+	}
+
+	for _, f := range fixtures {
+		name := strings.TrimSuffix(filepath.Base(f), ".json")
+
+		t.Run(name, func(t *testing.T) {
+			b, err := ioutil.ReadFile(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var card Card
+			if err := json.Unmarshal(b, &card); err != nil {
+				t.Fatalf("invalid card fixture: %s", err)
+			}
+
+			opts, um := testStoryOptions()
+			if change, ok := storyFixtureOptions[name]; ok {
+				change(opts)
+			}
+
+			got := normalizedStory(t, storyFromCard(&card, opts, um, nil))
+
+			golden := filepath.Join("testdata", "stories", name+".golden.json")
+			if *update {
+				out, err := json.MarshalIndent(got, "", "  ")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := ioutil.WriteFile(golden, append(out, '\n'), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			gb, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%s, run go test -run TestStoryFromCard -update to create it", err)
+			}
+
+			var want interface{}
+			if err := json.Unmarshal(gb, &want); err != nil {
+				t.Fatalf("invalid golden story: %s", err)
+			}
+
+			if !reflect.DeepEqual(got, normalizeJSON(want)) {
+				out, _ := json.MarshalIndent(got, "", "  ")
+				t.Errorf("story differs from %s, run with -update if the change is intended:\n%s", golden, out)
+			}
+		})
+	}
+}
+
+// testStoryOptions are the options every fixture starts from, trello-alice and
+// trello-bob are mapped and any other member falls back to the import member
+func testStoryOptions() (*ClubhouseOptions, *UserMap) {
+	opts := &ClubhouseOptions{
+		Project:        &ch.Project{ID: 10},
+		State:          &ch.State{ID: 500},
+		StoryType:      "feature",
+		ImportMember:   &ch.Member{ID: "import-member"},
+		URLAttachments: urlAttachmentsLinkedFile,
+		CommentsMode:   commentsEach,
+		DueComplete:    dueCompleteKeep,
+	}
+
+	um := &UserMap{
+		BackupUserID: "import-member",
+		Mapping:      map[string]string{"trello-alice": "ch-alice", "trello-bob": "ch-bob"},
+		Emails:       map[string]string{"trello-alice": "alice@example.com", "trello-bob": "bob@example.com"},
+	}
+
+	return opts, um
+}
+
+// normalizedStory is the story as generic json without its zero values,
+// so the golden files only hold what the card sets
+func normalizedStory(t *testing.T, cs *ch.CreateStory) interface{} {
+	b, err := json.Marshal(cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	return normalizeJSON(v)
+}
+
+func normalizeJSON(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for k, e := range x {
+			if e = normalizeJSON(e); isZeroJSON(e) {
+				delete(x, k)
+			} else {
+				x[k] = e
+			}
+		}
+	case []interface{}:
+		for i := range x {
+			x[i] = normalizeJSON(x[i])
+		}
+	}
+
+	return v
+}
+
+func isZeroJSON(v interface{}) bool {
+	switch x := v.(type) {
+	case nil:
+		return true
+	case string:
+		return x == ""
+	case float64:
+		return x == 0
+	case bool:
+		return !x
+	case []interface{}:
+		return len(x) == 0
+	case map[string]interface{}:
+		return len(x) == 0
+	}
+
+	return false
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+)
+
+const (
+	templateCardsSkip          = "skip"
+	templateCardsImport        = "import"
+	templateCardsStoryTemplate = "story-template"
+
+	defaultTemplatePattern = `(?i)^\W*template\b|\bcopy me\b`
+)
+
+// storyTemplate is the clubhouse entity template made from a template card
+type storyTemplate struct {
+	Name          string              `json:"name"`
+	StoryContents storyTemplateFields `json:"story_contents"`
+}
+
+type storyTemplateFields struct {
+	Name            string               `json:"name"`
+	Description     string               `json:"description"`
+	StoryType       string               `json:"story_type"`
+	ProjectID       int64                `json:"project_id"`
+	WorkflowStateID int64                `json:"workflow_state_id"`
+	Labels          []storyTemplateLabel `json:"labels"`
+	Tasks           []storyTemplateTask  `json:"tasks"`
+}
+
+type storyTemplateLabel struct {
+	Name string `json:"name"`
+}
+
+type storyTemplateTask struct {
+	Description string `json:"description"`
+	Complete    bool   `json:"complete"`
+}
+
+// isTemplateCard is true for trello card templates and cards named as
+// templates such as "TEMPLATE - copy me"
+func (co *ClubhouseOptions) isTemplateCard(card *Card) bool {
+	return card.IsTemplate || co.TemplatePattern != nil && co.TemplatePattern.MatchString(card.Name)
+}
+
+func compileTemplatePattern(p string) *regexp.Regexp {
+	if p == "" {
+		return nil
+	}
+
+	return regexp.MustCompile(p)
+}
+
+// createStoryTemplate adds the card as a clubhouse story template
+func createStoryTemplate(card *Card, opts *ClubhouseOptions) (string, error) {
+	desc, _ := buildDescriptionWithOverflow(card, opts)
+
+	t := storyTemplate{
+		Name: card.Name,
+		StoryContents: storyTemplateFields{
+			Name:            card.Name,
+			Description:     desc,
+			StoryType:       opts.storyTypeFor(card),
+			ProjectID:       opts.Project.ID,
+			WorkflowStateID: opts.State.ID,
+		},
+	}
+
+	for _, l := range *buildLabels(card, opts) {
+		t.StoryContents.Labels = append(t.StoryContents.Labels, storyTemplateLabel{Name: l.Name})
+	}
+
+	for _, tk := range card.Tasks {
+		t.StoryContents.Tasks = append(t.StoryContents.Tasks, storyTemplateTask{Description: tk.Description, Complete: tk.Completed})
+	}
+
+	// Template ids are uuids unlike the story and epic ids
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := clubhouseRequest("POST", "/entity-templates", t, &created); err != nil {
+		return "", fmt.Errorf("creating story template: %s", err)
+	}
+
+	return created.ID, nil
+}
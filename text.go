@@ -0,0 +1,99 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	descriptionChecklistsInline = "inline"
+	descriptionChecklistsTasks  = "tasks"
+)
+
+var descriptionChecklistRegexp = regexp.MustCompile(`^\s*[-*+] \[([ xX])\]\s+(.+?)\s*$`)
+
+// transformText applies the selected text conversions to
+// the names, descriptions and comments of exported cards
+func (t *TrelloOptions) transformText(s string) string {
+	if t.ConvertEmoji {
+		s = convertEmojiShortcodes(s)
+	}
+
+	return s
+}
+
+// transformBody is transformText for descriptions and comments
+// which can also hold html pasted from emails
+func (t *TrelloOptions) transformBody(s string) string {
+	if t.ConvertHTML {
+		s = convertHTML(s)
+	}
+
+	if t.CleanTrelloMarkup {
+		s = cleanTrelloMarkup(s)
+	}
+
+	return t.transformText(s)
+}
+
+// splitText splits s into parts of at most max runes preferring
+// to break at the last new line, or space, within each part
+func splitText(s string, max int) []string {
+	var parts []string
+
+	for utf8.RuneCountInString(s) > max {
+		cut := runeOffset(s, max)
+
+		if i := strings.LastIndex(s[:cut], "\n"); i > cut/2 {
+			cut = i + 1
+		} else if i := strings.LastIndex(s[:cut], " "); i > cut/2 {
+			cut = i + 1
+		}
+
+		parts = append(parts, s[:cut])
+		s = s[cut:]
+	}
+
+	return append(parts, s)
+}
+
+// extractDescriptionChecklist removes the - [ ] markdown checklist items from
+// the description returning them as tasks, items in code blocks are left alone
+func extractDescriptionChecklist(desc string) (string, []Task) {
+	var tasks []Task
+	var kept []string
+	var inCode bool
+
+	for _, l := range strings.Split(desc, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(l), "```") {
+			inCode = !inCode
+		}
+
+		m := descriptionChecklistRegexp.FindStringSubmatch(l)
+		if inCode || m == nil {
+			kept = append(kept, l)
+			continue
+		}
+
+		tasks = append(tasks, Task{Completed: m[1] != " ", Description: m[2]})
+	}
+
+	if len(tasks) == 0 {
+		return desc, nil
+	}
+
+	return strings.TrimSpace(strings.Join(kept, "\n")), tasks
+}
+
+// runeOffset returns the byte offset of the n-th rune of s
+func runeOffset(s string, n int) int {
+	for i := range s {
+		if n == 0 {
+			return i
+		}
+		n--
+	}
+
+	return len(s)
+}
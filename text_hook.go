@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// TextHook rewrites the text of a card before it is imported, such as
+// translating it or scrubbing personal data
+type TextHook interface {
+	Transform(t *hookText) error
+}
+
+// hookText is the json sent to the hook and expected back with
+// the name, description and comments rewritten
+type hookText struct {
+	CardURL     string   `json:"card_url"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Comments    []string `json:"comments"`
+}
+
+// httpTextHook posts the text to an endpoint
+type httpTextHook struct {
+	URL    string
+	client *http.Client
+}
+
+// execTextHook runs a command with the text on stdin reading it back from stdout
+type execTextHook struct {
+	Args []string
+}
+
+// NewTextHook returns the hook for the option which is either the url
+// of an endpoint or a command to run for each card
+func NewTextHook(option string) TextHook {
+	switch {
+	case option == "":
+		return nil
+	case strings.HasPrefix(option, "http://") || strings.HasPrefix(option, "https://"):
+		return &httpTextHook{URL: option, client: &http.Client{Timeout: 30 * time.Second}}
+	}
+
+	args := strings.Fields(option)
+	if _, err := exec.LookPath(args[0]); err != nil {
+		fatalConfigf("Text hook command '%s' not found: %s", args[0], err)
+	}
+
+	return &execTextHook{Args: args}
+}
+
+func (h *httpTextHook) Transform(t *hookText) error {
+	b, _ := json.Marshal(t)
+
+	resp, err := h.client.Post(h.URL, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	rb, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("text hook returned %d: %s", resp.StatusCode, rb)
+	}
+
+	return readHookText(rb, t)
+}
+
+func (h *execTextHook) Transform(t *hookText) error {
+	b, _ := json.Marshal(t)
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(h.Args[0], h.Args[1:]...)
+	cmd.Stdin = bytes.NewReader(b)
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("text hook %s: %s %s", h.Args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return readHookText(out, t)
+}
+
+// readHookText replaces the text with the response, the comments must
+// all come back so they stay with their author and date
+func readHookText(b []byte, t *hookText) error {
+	var out hookText
+	if err := json.Unmarshal(b, &out); err != nil {
+		return fmt.Errorf("text hook response: %s", err)
+	}
+
+	if len(out.Comments) != len(t.Comments) {
+		return fmt.Errorf("text hook returned %d comments for %d", len(out.Comments), len(t.Comments))
+	}
+
+	t.Name, t.Description, t.Comments = out.Name, out.Description, out.Comments
+
+	return nil
+}
+
+// applyTextHook rewrites the name, description and comments of the card, the
+// card fails when the hook does so no text skips it (e.g. unscrubbed)
+func applyTextHook(h TextHook, card *Card) error {
+	if h == nil {
+		return nil
+	}
+
+	t := hookText{CardURL: card.ShortURL, Name: card.Name, Description: card.Desc}
+	for _, c := range card.Comments {
+		t.Comments = append(t.Comments, c.Text)
+	}
+
+	if err := h.Transform(&t); err != nil {
+		return err
+	}
+
+	card.Name, card.Desc = t.Name, t.Description
+	for i := range card.Comments {
+		card.Comments[i].Text = t.Comments[i]
+	}
+
+	return nil
+}
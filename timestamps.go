@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	createdAtFooterLayout  = "2006-01-02 15:04 MST"
+	createdYearLabelFormat = "created-%d"
+)
+
+// storyTime returns the timestamp clamped between the minimum created at
+// and now, as clubhouse rejects timestamps in the future or before the
+// workspace existed. nil is returned when historical dates are dropped
+func (co *ClubhouseOptions) storyTime(t *time.Time) *time.Time {
+	if t == nil || co.DropCreatedAt {
+		return nil
+	}
+
+	if now := time.Now(); t.After(now) {
+		return &now
+	}
+
+	if !co.MinCreatedAt.IsZero() && t.Before(co.MinCreatedAt) {
+		m := co.MinCreatedAt
+		return &m
+	}
+
+	return t
+}
+
+// deadline is the due date of the card, with -due-timezone it is moved to the
+// date it falls on in that timezone and sent at noon utc so clubhouse shows
+// the same date in any workspace timezone
+func (co *ClubhouseOptions) deadline(t *time.Time) *time.Time {
+	if t == nil || co.DueLocation == nil {
+		return t
+	}
+
+	y, m, d := t.In(co.DueLocation).Date()
+	due := time.Date(y, m, d, 12, 0, 0, 0, time.UTC)
+
+	return &due
+}
+
+// commentTime is storyTime for comments which always need a timestamp
+func (co *ClubhouseOptions) commentTime(t *time.Time) time.Time {
+	if st := co.storyTime(t); st != nil {
+		return *st
+	}
+
+	return time.Now()
+}
+
+// createdAtFooter keeps the original date in the text when
+// the historical created at isn't sent to clubhouse
+func (co *ClubhouseOptions) createdAtFooter(t *time.Time, format string) string {
+	if !co.DropCreatedAt || t == nil {
+		return ""
+	}
+
+	return fmt.Sprintf(format, t.UTC().Format(createdAtFooterLayout))
+}
+
+// createdYearLabel buckets the card by the year it was created in trello,
+// the original year is used even when the created at sent is clamped
+func createdYearLabel(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return fmt.Sprintf(createdYearLabelFormat, t.UTC().Year())
+}
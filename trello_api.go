@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	trello "github.com/jnormington/go-trello"
+)
+
+var trelloAPIURL = "https://api.trello.com/1"
+
+// trelloRequest calls the Trello api directly for the endpoints and
+// fields not covered by the go-trello package, out is json decoded
+func trelloRequest(method string, path string, params url.Values, out interface{}) error {
+	if params == nil {
+		params = url.Values{}
+	}
+
+	params.Set("key", trelloKey)
+	params.Set("token", trelloToken)
+
+	req, err := http.NewRequest(method, trelloAPIURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		return classifyError(fmt.Errorf("trello api %s %s returned %d: %s", method, path, resp.StatusCode, b))
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.Unmarshal(b, out)
+}
+
+// trelloLabel is a board label including its id and color
+type trelloLabel struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+func getBoardLabels(boardID string) ([]trelloLabel, error) {
+	var labels []trelloLabel
+	err := trelloRequest("GET", "/boards/"+boardID+"/labels", url.Values{"limit": {"1000"}}, &labels)
+
+	return labels, err
+}
+
+// trelloCardDetails holds the card fields missing from go-trello
+type trelloCardDetails struct {
+	DueComplete bool `json:"dueComplete"`
+	IsTemplate  bool `json:"isTemplate"`
+	Cover       struct {
+		Color string `json:"color"`
+	} `json:"cover"`
+}
+
+var trelloCardDetailFields = "dueComplete,cover,isTemplate"
+
+func getCardDetails(card *trello.Card) trelloCardDetails {
+	var d trelloCardDetails
+
+	err := trelloRequest("GET", "/cards/"+card.Id, url.Values{"fields": {trelloCardDetailFields}}, &d)
+	if err != nil {
+		fmt.Println("Error: Querying the details for:", card.Name, "ignoring...", err)
+	}
+
+	return d
+}
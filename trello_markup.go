@@ -0,0 +1,33 @@
+package main
+
+import "regexp"
+
+var (
+	// trelloSmartLinkRegexp matches the links trello's editor unfurls into cards,
+	// their title is the smart card style or an invisible character
+	trelloSmartLinkRegexp = regexp.MustCompile(`\[([^\]]*)\]\((\S+?)\s+"(?:smartCard-(?:inline|block|embed)|[\x{200B}\x{200C}\x{FEFF}]*)"\)`)
+
+	// trelloLinkJoinerRegexp is the zero width character trello leaves after a link
+	trelloLinkJoinerRegexp = regexp.MustCompile(`(\)|https?://\S+?)[\x{200B}\x{200C}\x{FEFF}]+`)
+
+	// trelloPreviewURLRegexp matches the attachment previews which only load inside trello,
+	// the attachment download url is used instead so it is rewritten like the others
+	trelloPreviewURLRegexp = regexp.MustCompile(`(https://trello\.com/1/cards/[0-9a-f]{24}/attachments/[0-9a-f]{24})/previews/[0-9a-f]{24}/download/`)
+)
+
+// cleanTrelloMarkup removes the markup only trello renders, smart links become
+// plain links (a bare url when the text is the url) without the invisible joiners
+func cleanTrelloMarkup(s string) string {
+	s = trelloSmartLinkRegexp.ReplaceAllStringFunc(s, func(m string) string {
+		sm := trelloSmartLinkRegexp.FindStringSubmatch(m)
+		if sm[1] == sm[2] || sm[1] == "" {
+			return sm[2]
+		}
+
+		return "[" + sm[1] + "](" + sm[2] + ")"
+	})
+
+	s = trelloLinkJoinerRegexp.ReplaceAllString(s, "$1")
+
+	return trelloPreviewURLRegexp.ReplaceAllString(s, "$1/download/")
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"strconv"
 	"strings"
@@ -11,73 +12,166 @@ import (
 
 // TrelloOptions stores options that the user has selected
 type TrelloOptions struct {
-	Board         *trello.Board
-	List          *trello.List
-	User          *trello.Member
-	ProcessImages bool
+	Board             *trello.Board
+	List              *trello.List
+	User              *trello.Member
+	Workspace         string
+	BoardName         string
+	ListName          string
+	Attachments       AttachmentOptions
+	FileNamePolicy    string
+	Concurrency       int
+	ConvertEmoji      bool
+	ConvertHTML       bool
+	CleanTrelloMarkup bool
+	FallbackCreator   string
+	DropboxPaths      *dropboxPather
+	Manifests         bool
+	ColorLabels       string
+	DescChecklists    string
+	CardTitles        *cardTitles
+	SavedExport       map[string]Card
+	AttachmentKey     string
+	LinkSettings      *sharedLinkSettings
+	CommentFilter     commentFilter
+
+	uploadSlots  chan struct{}
+	boardAdminID string
+	memberNames  map[string]string
+
+	onlyMemberIDs map[string]bool
+	onlyCardID    string
 }
 
 // SetupTrelloOptionsFromUser calls all the functions which consist of questions
 // for building TrelloOptions and returns a pointer to TrelloOptions instance
-func SetupTrelloOptionsFromUser() *TrelloOptions {
+func SetupTrelloOptionsFromUser(cfg *Config) *TrelloOptions {
 	var t TrelloOptions
 
-	t.promptUserShouldMigrateAttachments()
+	t.Workspace = cfg.Workspace
+	t.BoardName = cfg.Board
+	t.ListName = cfg.List
+	t.FileNamePolicy = cfg.FileNamePolicy
+	t.Attachments = newAttachmentOptions(cfg)
+	t.Concurrency = cfg.Concurrency
+	t.ConvertEmoji = cfg.ConvertEmoji
+	t.ConvertHTML = cfg.ConvertHTML
+	t.CleanTrelloMarkup = cfg.CleanTrelloMarkup
+	t.uploadSlots = make(chan struct{}, cfg.Concurrency)
+	t.FallbackCreator = cfg.FallbackCreator
+	t.DropboxPaths = newDropboxPather(cfg.DropboxPathTemplate, cfg.FileNamePolicy)
+	t.Manifests = cfg.AttachmentManifest
+	t.ColorLabels = cfg.ColorLabels
+	t.DescChecklists = cfg.DescriptionChecklists
+	t.SavedExport = loadExportedCards(cfg.FromExport)
+	t.CommentFilter = newCommentFilter(cfg.SkipBotComments, cfg.SkipCommentsBy, cfg.CommentsSince, cfg.SkipComments)
+	t.LinkSettings = newSharedLinkSettings(cfg.LinkAudience, cfg.LinkExpiry, cfg.LinkPassword)
+	if cfg.EncryptAttachments {
+		t.AttachmentKey = loadAttachmentKey(cfg.AttachmentKeyFile)
+	}
+	dateLayouts = append(cfg.DateLayouts, dateLayouts...)
+
+	t.Attachments.promptUser()
 	t.getCurrentUser()
+	t.useCardBoardAndList(cfg.Card)
 	t.getBoardsAndPromptUser()
 	t.getListsAndPromptUser()
+	t.resolveOnlyMembers(cfg.OnlyMembers)
+	t.findBoardAdmin()
+	t.loadManifestMemberNames()
 
 	return &t
 }
 
-func (t *TrelloOptions) promptUserShouldMigrateAttachments() {
-	fmt.Println("Would you like to migrate all attachments from trello cards?")
-	fmt.Println("This will entail downloading the attachments and uploading to dropbox")
-	fmt.Println("A dropbox account will be required for the token")
-
-	for i, b := range yesNoOpts {
-		fmt.Printf("[%d] %s\n", i, b)
-	}
-
-	i := promptUserSelectResource()
-	if i >= len(yesNoOpts) {
-		log.Fatal(errOutOfRange)
-	}
-
-	if i == 0 {
-		t.ProcessImages = true
-		if dropboxToken == "" {
-			log.Fatal("Dropbox token not supplied unable to continue")
-		}
-	}
-}
-
 func (t *TrelloOptions) getCurrentUser() {
+	promptForTrelloToken()
+
 	c, err := trello.NewAuthClient(trelloKey, &trelloToken)
 	if err != nil {
-		log.Fatal(err)
+		fatalConfig(err)
 	}
 
 	u, err := c.Member("me")
 	if err != nil {
-		log.Fatal(err)
+		fatalConfig(err)
 	}
 
 	t.User = u
 }
 
 func (t *TrelloOptions) getBoardsAndPromptUser() {
-	boards, err := t.User.Boards()
+	all, err := t.User.Boards()
 	if err != nil {
-		log.Fatal(err)
+		fatalConfig(trelloPermissionError("boards", err))
+	}
+
+	workspaces, err := getWorkspaces()
+	if err != nil {
+		fmt.Println("Error: Querying your workspaces ignoring...", err)
+	}
+
+	// Order the boards as they are displayed grouped by workspace
+	var boards []trello.Board
+	var names []string
+	boardWorkspace := map[string]string{}
+	groups := groupBoardsByWorkspace(all, workspaces, t.Workspace)
+	for _, g := range groups {
+		boards = append(boards, g.Boards...)
+		for _, b := range g.Boards {
+			names = append(names, b.Name)
+			boardWorkspace[b.Id] = g.Name
+		}
+	}
+	dups := duplicateNames(names)
+
+	if len(boards) == 0 {
+		promptRecovery("No boards found", explainNoBoards(t.Workspace, workspaces), "Enter a different Trello key and token")
+		trelloKey, trelloToken = "", ""
+		t.getCurrentUser()
+		t.getBoardsAndPromptUser()
+		return
+	}
+
+	if t.BoardName != "" {
+		var matches []int
+		for i, b := range boards {
+			if b.Id == t.BoardName {
+				t.Board = &boards[i]
+				return
+			}
+
+			if sameName(b.Name, t.BoardName) {
+				matches = append(matches, i)
+			}
+		}
+
+		switch len(matches) {
+		case 0:
+			fatalConfigf("Board '%s' not found", t.BoardName)
+		case 1:
+			t.Board = &boards[matches[0]]
+			return
+		}
+
+		var found []string
+		for _, i := range matches {
+			found = append(found, choiceName(boards[i].Name, boards[i].Id, boardWorkspace[boards[i].Id], dups))
+		}
+		fatalAmbiguousName("Board", t.BoardName, found)
 	}
 
 	fmt.Println("Please select a board by its number")
-	for i, b := range boards {
-		fmt.Printf("[%d] %s\n", i, b.Name)
+	var i int
+	for _, g := range groups {
+		fmt.Printf("Workspace: %s\n", g.Name)
+
+		for _, b := range g.Boards {
+			fmt.Printf("[%d] %s\n", i, choiceName(b.Name, b.Id, "", dups))
+			i++
+		}
 	}
 
-	i := promptUserSelectResource()
+	i = promptUserSelectResource()
 	if i >= len(boards) {
 		log.Fatal(errOutOfRange)
 	}
@@ -88,12 +182,59 @@ func (t *TrelloOptions) getBoardsAndPromptUser() {
 func (t *TrelloOptions) getListsAndPromptUser() {
 	lists, err := t.Board.Lists()
 	if err != nil {
-		log.Fatal(err)
+		fatalConfig(trelloPermissionError("the lists of board "+t.Board.Name, err))
+	}
+
+	if len(lists) == 0 {
+		promptRecovery("No lists found on board "+t.Board.Name,
+			[]string{"the board is empty or all its lists are archived"}, "Choose a different board")
+		t.BoardName = ""
+		t.getBoardsAndPromptUser()
+		t.getListsAndPromptUser()
+		return
+	}
+
+	var names []string
+	for _, l := range lists {
+		names = append(names, l.Name)
+	}
+	dups := duplicateNames(names)
+
+	listPosition := func(i int) string {
+		return fmt.Sprintf("list %d of %d from the left", i+1, len(lists))
+	}
+
+	if t.ListName != "" {
+		var matches []int
+		for i, l := range lists {
+			if l.Id == t.ListName {
+				t.List = &lists[i]
+				return
+			}
+
+			if sameName(l.Name, t.ListName) {
+				matches = append(matches, i)
+			}
+		}
+
+		switch len(matches) {
+		case 0:
+			fatalConfigf("List '%s' not found on board '%s'", t.ListName, t.Board.Name)
+		case 1:
+			t.List = &lists[matches[0]]
+			return
+		}
+
+		var found []string
+		for _, i := range matches {
+			found = append(found, choiceName(lists[i].Name, lists[i].Id, listPosition(i), dups))
+		}
+		fatalAmbiguousName("List", t.ListName, found)
 	}
 
 	fmt.Println("Please select the list to import by number")
 	for i, l := range lists {
-		fmt.Printf("[%d] %s\n", i, l.Name)
+		fmt.Printf("[%d] %s\n", i, choiceName(l.Name, l.Id, listPosition(i), dups))
 	}
 
 	i := promptUserSelectResource()
@@ -112,18 +253,11 @@ func (t TrelloOptions) getCards() []trello.Card {
 		log.Fatal(err)
 	}
 
-	return cards
+	return t.onlyMembersCards(t.onlyCardOfList(cards))
 }
 
 func promptUserSelectResource() int {
-	i, err := stdinReader.ReadString('\n')
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	i = strings.TrimRight(i, "\n")
-	i = strings.TrimRight(i, "\r")
-	id, err := strconv.Atoi(i)
+	id, err := strconv.Atoi(readInputLine())
 	if err != nil {
 		log.Fatal("Hmm... did you type a number from the list ?")
 	}
@@ -131,6 +265,25 @@ func promptUserSelectResource() int {
 	return id
 }
 
+func promptUserForText() string {
+	return readInputLine()
+}
+
+// readInputLine reads an answer handling both LF and CRLF line endings,
+// the byte order mark some Windows terminals send and a missing final newline
+func readInputLine() string {
+	if nonInteractive {
+		fatalConfig("A question needs answering but -non-interactive is set, give its answer with a flag, environment variable or the config file")
+	}
+
+	s, err := stdinReader.ReadString('\n')
+	if err != nil && (err != io.EOF || s == "") {
+		log.Fatal(err)
+	}
+
+	return strings.TrimSpace(strings.TrimPrefix(s, "\ufeff"))
+}
+
 // ListMembers gets the members for the selected board.
 // And fails hard if an err occurs.
 func (t TrelloOptions) ListMembers() *[]trello.Member {
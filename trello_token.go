@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+)
+
+const (
+	trelloAppKeyURL     = "https://trello.com/app-key"
+	trelloTokenAttempts = 3
+)
+
+// tokenMissing returns true when the token is empty or still the placeholder
+func tokenMissing(token string) bool {
+	return token == "" || token == "YOURTOKEN" || token == "YOURKEY"
+}
+
+// promptForTrelloToken guides getting the trello key and token in the browser
+// when they weren't supplied, the token is pasted back, checked and can be
+// stored encrypted for the next runs
+func promptForTrelloToken() {
+	if !tokenMissing(trelloKey) && !tokenMissing(trelloToken) {
+		return
+	}
+
+	if nonInteractive {
+		fatalConfig("The Trello key and token are needed, set TRELLO_KEY and TRELLO_TOKEN")
+	}
+
+	fmt.Println("****** TRELLO TOKEN ******")
+	fmt.Println("No Trello token was supplied, let's get one in your browser")
+
+	if tokenMissing(trelloKey) {
+		fmt.Println("Log in to Trello and copy the key shown at the top of the page, if it doesn't open visit:", trelloAppKeyURL)
+		openBrowser(trelloAppKeyURL)
+		fmt.Println("Please paste your Trello key")
+		trelloKey = promptUserForText()
+	}
+
+	for i := 1; ; i++ {
+		u := trelloAuthorizeURL + "?" + url.Values{
+			"expiration":    {"never"},
+			"name":          {trelloAppName},
+			"scope":         {"read,write"},
+			"response_type": {"token"},
+			"key":           {trelloKey},
+		}.Encode()
+
+		fmt.Println("Click Allow and copy the token shown, if the page doesn't open visit:", u)
+		openBrowser(u)
+		fmt.Println("Please paste your Trello token")
+		trelloToken = promptUserForText()
+
+		var me struct {
+			Username string `json:"username"`
+		}
+
+		err := trelloRequest("GET", "/members/me", url.Values{"fields": {"username"}}, &me)
+		if err == nil {
+			fmt.Printf("Trello token works, logged in as %s\n", me.Username)
+			break
+		}
+
+		if i == trelloTokenAttempts {
+			fatalConfigf("The Trello key and token don't work: %s", err)
+		}
+		fmt.Println("That token doesn't work, check the key and try again...", err)
+	}
+
+	offerToStoreTrelloToken()
+}
+
+// offerToStoreTrelloToken saves the key and token with the stored credentials
+func offerToStoreTrelloToken() {
+	fmt.Println("Would you like the key and token stored encrypted for next time?")
+	for i, b := range yesNoOpts {
+		fmt.Printf("[%d] %s\n", i, b)
+	}
+
+	if promptUserSelectResource() != 0 {
+		return
+	}
+
+	passphrase := promptPassphrase()
+	c := &Credentials{}
+	if credentialsExist() {
+		var err error
+		if c, err = loadCredentials(passphrase); err != nil {
+			fmt.Println("Error: Loading stored credentials not storing the token...", err)
+			return
+		}
+	}
+
+	c.TrelloKey, c.TrelloToken = trelloKey, trelloToken
+	if err := saveCredentials(c, passphrase); err != nil {
+		fmt.Println("Error: Storing the token continuing...", err)
+		return
+	}
+
+	fmt.Printf("*********************\n Credentials saved: %s\n*********************\n", getCredentialsPath())
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	ch "github.com/jnormington/clubhouse-go"
+)
+
+const (
+	modeCreate = "create"
+	modeUpdate = "update"
+)
+
+// storyUpdate is the patch of an existing story, the clubhouse package leaves
+// out an empty deadline so it couldn't be cleared when the due date is removed
+type storyUpdate struct {
+	Name            string           `json:"name"`
+	Description     string           `json:"description"`
+	Deadline        *time.Time       `json:"deadline"`
+	Labels          []ch.CreateLabel `json:"labels"`
+	WorkflowStateID int64            `json:"workflow_state_id,omitempty"`
+}
+
+// buildStoryUpdate builds the patch making an existing story match the
+// current card, only the name, description, labels, deadline and state
+// are updated so comments, tasks and files aren't added twice. The state
+// is only changed when the card moved to another list since the last run,
+// or its due date was completed, so stories moved on in clubhouse stay put
+func buildStoryUpdate(card *Card, opts *ClubhouseOptions) *storyUpdate {
+	desc, _ := buildDescriptionWithOverflow(card, opts)
+
+	cs := &ch.CreateStory{
+		Name:            card.Name,
+		Description:     desc,
+		Deadline:        opts.deadline(card.DueDate),
+		Labels:          *buildLabels(card, opts),
+		WorkflowStateID: opts.State.ID,
+	}
+	applyDueComplete(cs, card, opts)
+
+	u := &storyUpdate{
+		Name:        cs.Name,
+		Description: cs.Description,
+		Deadline:    cs.Deadline,
+		Labels:      cs.Labels,
+	}
+
+	l := opts.StoryLists[card.ShortURL]
+	if (l != "" && l != card.IDList) || cs.WorkflowStateID != opts.State.ID {
+		u.WorkflowStateID = cs.WorkflowStateID
+	}
+
+	return u
+}
+
+// updateExistingStory patches the story mapped to the card
+func updateExistingStory(card *Card, storyID int64, opts *ClubhouseOptions) error {
+	return clubhouseRequest("PUT", fmt.Sprintf("/stories/%d", storyID), buildStoryUpdate(card, opts), nil)
+}
@@ -22,6 +22,9 @@ type UserMap struct {
 
 	GenerateCSV bool
 	Mapping     map[string]string
+	// Emails is the clubhouse email given for each trello member id
+	// which is kept even when the email isn't a clubhouse member yet
+	Emails map[string]string
 }
 
 // NewUserMap initializes a UserMap struct with trello and clubhouse members
@@ -32,12 +35,19 @@ func NewUserMap(to *TrelloOptions, co *ClubhouseOptions) *UserMap {
 	um.ClubhouseMembers = co.ListMembers()
 	um.BackupUserID = co.ImportMember.ID
 	um.Mapping = make(map[string]string)
+	um.Emails = make(map[string]string)
 
 	return &um
 }
 
 // SetupUserMapping calls all the internal prompt functions
 func (um *UserMap) SetupUserMapping() {
+	if nonInteractive {
+		// The csv must already be correct, there is nobody to edit it
+		um.buildUserMapFromCSV()
+		return
+	}
+
 	um.promptShouldGenerateCSV()
 
 	if um.GenerateCSV {
@@ -99,7 +109,7 @@ func (um UserMap) buildUserMapToFile() {
 				continue
 			}
 
-			if m.FullName == u.Profile.Name {
+			if sameName(m.FullName, u.Profile.Name) {
 				email := u.Profile.EmailAddress
 				users = append(users, []string{m.Username, email})
 				continue
@@ -131,14 +141,14 @@ func (um *UserMap) buildUserMapFromCSV() {
 	f, err := os.Open(getCSVPath())
 
 	if err != nil {
-		log.Fatalf("Error opening user mapping file: %s", err)
+		fatalConfigf("Error opening user mapping file: %s", err)
 	}
 
 	r := csv.NewReader(f)
 
 	users, err := r.ReadAll()
 	if err != nil {
-		log.Fatalf("Error reading user mapping file: %s", err)
+		fatalConfigf("Error reading user mapping file: %s", err)
 	}
 
 	for i, u := range users {
@@ -153,7 +163,21 @@ func (um *UserMap) buildUserMapFromCSV() {
 			tm := um.getTrelloMemberID(u[0])
 			cu := um.getClubhouseUserID(u[1])
 			um.Mapping[tm] = cu
+			um.Emails[tm] = u[1]
+		}
+	}
+}
+
+func (um *UserMap) buildUserMapFromMapping(m *Mapping) {
+	for _, mm := range m.Members {
+		if mm.Trello == "" {
+			continue
 		}
+
+		tm := um.getTrelloMemberID(mm.Trello)
+		cu := um.getClubhouseUserID(mm.Clubhouse)
+		um.Mapping[tm] = cu
+		um.Emails[tm] = mm.Clubhouse
 	}
 }
 
@@ -170,7 +194,7 @@ func (um UserMap) getClubhouseUserID(email string) string {
 
 func (um UserMap) getTrelloMemberID(username string) string {
 	for _, m := range *um.TrelloMembers {
-		if m.Username == username {
+		if sameName(m.Username, username) {
 			return m.Id
 		}
 	}
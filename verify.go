@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strings"
+
+	ch "github.com/jnormington/clubhouse-go"
+)
+
+const defaultAuditFile = "verifyReportTtoC.json"
+
+// ExpectedStory is what was sent to clubhouse for the card,
+// recorded in the report so the story can be verified later
+type ExpectedStory struct {
+	Name        string   `json:"name"`
+	Comments    int      `json:"comments"`
+	Tasks       int      `json:"tasks"`
+	Labels      []string `json:"labels"`
+	LinkedFiles int      `json:"linked_files"`
+}
+
+// AuditResult is the verification of a single story
+type AuditResult struct {
+	CardURL    string   `json:"card_url"`
+	StoryID    int64    `json:"story_id"`
+	Passed     bool     `json:"passed"`
+	Mismatches []string `json:"mismatches,omitempty"`
+}
+
+// clubhouseStoryDetails holds the story fields compared by verify
+type clubhouseStoryDetails struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Comments []struct {
+		ID int64 `json:"id"`
+	} `json:"comments"`
+	Tasks []struct {
+		ID int64 `json:"id"`
+	} `json:"tasks"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	LinkedFileIDs []int64 `json:"linked_file_ids"`
+}
+
+func newExpectedStory(cs *ch.CreateStory, extraComments int) *ExpectedStory {
+	e := ExpectedStory{
+		Name:        cs.Name,
+		Comments:    len(cs.Comments) + extraComments,
+		Tasks:       len(cs.Tasks),
+		LinkedFiles: len(cs.LinkedFileIds),
+	}
+
+	for _, l := range cs.Labels {
+		e.Labels = append(e.Labels, l.Name)
+	}
+
+	return &e
+}
+
+// runVerifyCommand audits the stories in a migration report
+func runVerifyCommand(args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	reportPath := fs.String("report", defaultReportFile, "path of the migration report to verify")
+	out := fs.String("o", defaultAuditFile, "path the json audit report is written to")
+	output := fs.String("output", outputAuto, "how the card results are printed: table, json or csv, json when stdout isn't a terminal")
+	fs.Parse(args)
+
+	setupOutput(*output)
+
+	applyTokens()
+
+	r, err := loadReport(*reportPath)
+	if err != nil {
+		log.Fatalf("Error reading migration report: %s", err)
+	}
+
+	results := verifyReport(r)
+	writeAuditReport(results, *out)
+}
+
+func loadReport(path string) (*Report, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := newReport()
+	if err := json.Unmarshal(b, r); err != nil {
+		return nil, err
+	}
+
+	for _, c := range r.Cards {
+		r.index[c.CardURL] = c
+	}
+
+	return r, nil
+}
+
+// verifyReport fetches every created story and compares it
+// field by field against what was sent for the card
+func verifyReport(r *Report) []AuditResult {
+	var results []AuditResult
+
+	fmt.Println("Verifying imported stories...")
+	cardOutput.Header("Trello Card Link", "Audit Status", "Mismatches")
+
+	for _, c := range r.Cards {
+		if c.StoryID == 0 || c.Expected == nil {
+			continue
+		}
+
+		a := AuditResult{CardURL: c.CardURL, StoryID: c.StoryID}
+
+		var st clubhouseStoryDetails
+		if err := clubhouseRequest("GET", fmt.Sprintf("/stories/%d", c.StoryID), nil, &st); err != nil {
+			a.Mismatches = []string{fmt.Sprintf("story could not be fetched: %s", err)}
+		} else {
+			a.Mismatches = compareStory(c.Expected, &st)
+		}
+
+		a.Passed = len(a.Mismatches) == 0
+		results = append(results, a)
+
+		status := "Pass"
+		if !a.Passed {
+			status = "Fail"
+		}
+		cardOutput.Row(c.CardURL, status, strings.Join(a.Mismatches, ", "))
+	}
+
+	return results
+}
+
+func compareStory(e *ExpectedStory, st *clubhouseStoryDetails) []string {
+	var m []string
+
+	if e.Name != st.Name {
+		m = append(m, fmt.Sprintf("name: expected '%s' got '%s'", e.Name, st.Name))
+	}
+
+	counts := []struct {
+		field    string
+		expected int
+		got      int
+	}{
+		{"comments", e.Comments, len(st.Comments)},
+		{"tasks", e.Tasks, len(st.Tasks)},
+		{"linked files", e.LinkedFiles, len(st.LinkedFileIDs)},
+	}
+
+	for _, c := range counts {
+		if c.expected != c.got {
+			m = append(m, fmt.Sprintf("%s: expected %d got %d", c.field, c.expected, c.got))
+		}
+	}
+
+	var labels []string
+	for _, l := range st.Labels {
+		labels = append(labels, l.Name)
+	}
+
+	if !sameStringSet(e.Labels, labels) {
+		m = append(m, fmt.Sprintf("labels: expected [%s] got [%s]", strings.Join(e.Labels, ","), strings.Join(labels, ",")))
+	}
+
+	return m
+}
+
+func sameStringSet(a []string, b []string) bool {
+	x := append([]string{}, a...)
+	y := append([]string{}, b...)
+	sort.Strings(x)
+	sort.Strings(y)
+
+	return strings.Join(x, "\x00") == strings.Join(y, "\x00")
+}
+
+func writeAuditReport(results []AuditResult, path string) {
+	var failed int
+	for _, r := range results {
+		if !r.Passed {
+			failed++
+		}
+	}
+
+	b, err := json.MarshalIndent(results, "", "  ")
+	if err == nil {
+		err = ioutil.WriteFile(path, b, 0644)
+	}
+
+	if err != nil {
+		fmt.Println("Error: Writing the audit report ignoring...", err)
+	}
+
+	fmt.Printf("*********************\n Verified %d stories, %d failed\n Audit report: %s\n*********************\n", len(results), failed, path)
+}
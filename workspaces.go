@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	trello "github.com/jnormington/go-trello"
+)
+
+const personalWorkspace = "Personal boards"
+
+// trelloWorkspace is a trello organization the user belongs to
+type trelloWorkspace struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+func getWorkspaces() (map[string]trelloWorkspace, error) {
+	var ws []trelloWorkspace
+	err := trelloRequest("GET", "/members/me/organizations", url.Values{"fields": {"id,name,displayName"}}, &ws)
+
+	m := map[string]trelloWorkspace{}
+	for _, w := range ws {
+		m[w.ID] = w
+	}
+
+	return m, err
+}
+
+// workspaceBoards holds the boards in a workspace for displaying grouped
+type workspaceBoards struct {
+	Name   string
+	Boards []trello.Board
+}
+
+// groupBoardsByWorkspace groups the boards by their workspace, sorted by
+// workspace name with personal boards last, filtering to the workspace
+// matching the name, display name or id when given
+func groupBoardsByWorkspace(boards []trello.Board, workspaces map[string]trelloWorkspace, filter string) []workspaceBoards {
+	grouped := map[string]*workspaceBoards{}
+
+	for _, b := range boards {
+		name := personalWorkspace
+		if w, ok := workspaces[b.IdOrganization]; ok {
+			name = w.DisplayName
+
+			if filter != "" && !strings.EqualFold(filter, w.Name) && !strings.EqualFold(filter, w.DisplayName) && filter != w.ID {
+				continue
+			}
+		} else if b.IdOrganization != "" {
+			// Board in a workspace the user isn't a member of
+			name = fmt.Sprintf("Workspace %s", b.IdOrganization)
+
+			if filter != "" && filter != b.IdOrganization {
+				continue
+			}
+		} else if filter != "" {
+			continue
+		}
+
+		if grouped[name] == nil {
+			grouped[name] = &workspaceBoards{Name: name}
+		}
+		grouped[name].Boards = append(grouped[name].Boards, b)
+	}
+
+	var out []workspaceBoards
+	for _, g := range grouped {
+		out = append(out, *g)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if (out[i].Name == personalWorkspace) != (out[j].Name == personalWorkspace) {
+			return out[j].Name == personalWorkspace
+		}
+
+		return out[i].Name < out[j].Name
+	})
+
+	return out
+}
+
+// explainNoBoards returns the likely reasons no boards were found
+func explainNoBoards(workspace string, workspaces map[string]trelloWorkspace) []string {
+	if workspace != "" {
+		return []string{fmt.Sprintf("the workspace '%s' is misspelt or your token has no access to it", workspace)}
+	}
+
+	if len(workspaces) > 0 {
+		return []string{"enterprise workspaces can restrict api access to their boards, ask your enterprise admin to allow the token",
+			"the token belongs to a different Trello account than the one with the boards"}
+	}
+
+	return []string{"the token belongs to a different Trello account than the one with the boards",
+		"the Trello account has no open boards"}
+}
+
+// trelloPermissionError makes unauthorized api errors clearer
+func trelloPermissionError(resource string, err error) string {
+	e := err.Error()
+	if errorCause(classifyError(err)) == causeAuth {
+		return fmt.Sprintf("Permission denied reading %s, the board may be restricted by an enterprise policy or your token lacks access: %s", resource, e)
+	}
+
+	return e
+}